@@ -2,7 +2,11 @@ module github.com/mikeraimondi/gurnel
 
 go 1.18
 
-require github.com/mikeraimondi/frontmatter/v2 v2.0.2
+require (
+	github.com/mikeraimondi/frontmatter/v2 v2.0.2
+	github.com/yuin/goldmark v1.5.6
+	golang.org/x/crypto v0.14.0
+)
 
 require (
 	github.com/kr/text v0.2.0 // indirect
@@ -2,14 +2,117 @@ package gurnel
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"text/template"
 	"time"
 )
 
+const (
+	// beeminderTimeout bounds each request to the Beeminder API.
+	beeminderTimeout = 30 * time.Second
+	// beeminderRetries is how many times a rate-limited request is retried.
+	beeminderRetries = 3
+	// beeminderMaxWait is the longest a rate-limited request waits before
+	// retrying.
+	beeminderMaxWait = time.Minute
+	// daystampFormat is the format of Beeminder's daystamps.
+	daystampFormat = "20060102"
+	// defaultComment is the comment template for datapoints.
+	defaultComment = `via Gurnel at {{.Time.Format "15:04:05 MST"}}`
+)
+
+// BeeminderGoalConfig describes a Beeminder goal that entries are reported
+// to.
+type BeeminderGoalConfig struct {
+	Slug string
+	// Metric is the value posted for an entry: "words" (the default),
+	// "minutes" spent writing, "binary" (1 for each day written), or "mood"
+	// (the average mood).
+	Metric string
+	// Comment is a text/template for the datapoint's comment. It is given
+	// the Time of posting, the entry's Date, Words, Minutes, and Mood, and
+	// the posted Value. It defaults to "via Gurnel at" and the time.
+	Comment string
+}
+
+// commentData is given to comment templates.
+type commentData struct {
+	Time    time.Time
+	Date    time.Time
+	Words   int
+	Minutes int
+	Mood    uint8
+	Value   float64
+}
+
+// datapoint returns the value and comment to post to the goal for p, at the
+// time t.
+func (gc *BeeminderGoalConfig) datapoint(p *Entry, t time.Time) (float64, string, error) {
+	data := commentData{
+		Time:    t,
+		Words:   len(p.Words()),
+		Minutes: int(math.Round(time.Duration(p.TimeSpent).Minutes())),
+		Mood:    p.AverageMood,
+	}
+	if date, err := p.Date(); err == nil {
+		data.Date = date
+	}
+	switch gc.Metric {
+	case "", "words":
+		data.Value = float64(data.Words)
+	case "minutes":
+		data.Value = float64(data.Minutes)
+	case "binary":
+		data.Value = 1
+	case "mood":
+		data.Value = float64(data.Mood)
+	default:
+		return 0, "", fmt.Errorf("unknown metric %q for goal %s", gc.Metric, gc.Slug)
+	}
+
+	text := gc.Comment
+	if text == "" {
+		text = defaultComment
+	}
+	tmpl, err := template.New("comment").Parse(text)
+	if err != nil {
+		return 0, "", fmt.Errorf("parsing comment for goal %s: %w", gc.Slug, err)
+	}
+	var comment strings.Builder
+	if err := tmpl.Execute(&comment, data); err != nil {
+		return 0, "", fmt.Errorf("executing comment for goal %s: %w", gc.Slug, err)
+	}
+	return data.Value, comment.String(), nil
+}
+
+// reportEntry posts p to each of the configured Beeminder goals, at the time
+// t. The datapoints count toward the day of p.
+func reportEntry(ctx context.Context, client *beeminderClient, goals []BeeminderGoalConfig, p *Entry, t time.Time) error {
+	day, err := p.Date()
+	if err != nil {
+		day = t
+	}
+	for i := range goals {
+		value, comment, err := goals[i].datapoint(p, t)
+		if err != nil {
+			return err
+		}
+		if err := client.postDatapoint(ctx, goals[i].Slug, value, comment, day, t); err != nil {
+			return fmt.Errorf("posting to %s: %w", goals[i].Slug, err)
+		}
+	}
+	return nil
+}
+
 type beeminderClient struct {
 	Token     []byte
 	User      string
@@ -17,6 +120,59 @@ type beeminderClient struct {
 	serverURL string
 }
 
+// beeminderGoal is the part of a Beeminder goal gurnel uses.
+type beeminderGoal struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	// SafeBuf is the number of days before the goal derails.
+	SafeBuf int `json:"safebuf"`
+	// Rate is the slope of the bright red line, per RUnits.
+	Rate   float64 `json:"rate"`
+	RUnits string  `json:"runits"`
+	// LoseDate is when the goal derails, as a Unix time.
+	LoseDate int64 `json:"losedate"`
+	// Limsum summarizes what's needed to stay on track, like "+2 due in 1
+	// day".
+	Limsum string  `json:"limsum"`
+	CurVal float64 `json:"curval"`
+}
+
+// Derails returns when the goal will derail.
+func (g *beeminderGoal) Derails() time.Time {
+	return time.Unix(g.LoseDate, 0)
+}
+
+// beeminderDatapoint is a datapoint of a Beeminder goal.
+type beeminderDatapoint struct {
+	ID        string  `json:"id,omitempty"`
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp,omitempty"`
+	// Daystamp is the day the datapoint counts toward, in daystampFormat.
+	Daystamp string `json:"daystamp,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	// RequestID makes creating the datapoint idempotent. A second datapoint
+	// with the same RequestID updates the first.
+	RequestID string `json:"requestid,omitempty"`
+}
+
+func (dp *beeminderDatapoint) values() url.Values {
+	v := url.Values{}
+	v.Set("value", strconv.FormatFloat(dp.Value, 'f', -1, 64))
+	if dp.Timestamp != 0 {
+		v.Set("timestamp", strconv.FormatInt(dp.Timestamp, 10))
+	}
+	if dp.Daystamp != "" {
+		v.Set("daystamp", dp.Daystamp)
+	}
+	if dp.Comment != "" {
+		v.Set("comment", dp.Comment)
+	}
+	if dp.RequestID != "" {
+		v.Set("requestid", dp.RequestID)
+	}
+	return v
+}
+
 func newBeeminderClient(user string, token []byte) (*beeminderClient, error) {
 	if user == "" {
 		return nil, fmt.Errorf("user must not be blank")
@@ -28,46 +184,212 @@ func newBeeminderClient(user string, token []byte) (*beeminderClient, error) {
 	return &beeminderClient{
 		Token:     bytes.TrimSpace(token),
 		User:      user,
+		c:         http.Client{Timeout: beeminderTimeout},
 		serverURL: "https://www.beeminder.com",
 	}, nil
 }
 
+// postDatapoint adds value to goal for day, at the time t. Posting again for
+// the same day replaces the earlier datapoint rather than adding to it.
 func (client *beeminderClient) postDatapoint(
+	ctx context.Context,
 	goal string,
-	count int,
+	value float64,
+	comment string,
+	day time.Time,
 	t time.Time,
 ) error {
+	if value < 0 {
+		return fmt.Errorf("value must be nonnegative")
+	}
+	daystamp := day.Format(daystampFormat)
+	_, err := client.createDatapoint(ctx, goal, &beeminderDatapoint{
+		Value:     value,
+		Timestamp: t.Unix(),
+		Daystamp:  daystamp,
+		Comment:   comment,
+		RequestID: "gurnel-" + daystamp,
+	})
+	return err
+}
+
+// goal fetches the details of goal.
+func (client *beeminderClient) goal(ctx context.Context, goal string) (*beeminderGoal, error) {
+	if goal == "" {
+		return nil, fmt.Errorf("goal must not be blank")
+	}
+	var g beeminderGoal
+	if err := client.do(ctx, http.MethodGet, client.goalPath(goal)+".json", nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// datapoints lists the datapoints of goal, newest first.
+func (client *beeminderClient) datapoints(ctx context.Context, goal string) ([]beeminderDatapoint, error) {
 	if goal == "" {
-		return fmt.Errorf("goal must not be blank")
+		return nil, fmt.Errorf("goal must not be blank")
 	}
-	if count < 0 {
-		return fmt.Errorf("count must be nonnegative")
+	var dps []beeminderDatapoint
+	err := client.do(ctx, http.MethodGet, client.goalPath(goal)+"/datapoints.json", nil, &dps)
+	return dps, err
+}
+
+// createDatapoint adds dp to goal, returning the datapoint as created.
+func (client *beeminderClient) createDatapoint(
+	ctx context.Context,
+	goal string,
+	dp *beeminderDatapoint,
+) (*beeminderDatapoint, error) {
+	if goal == "" {
+		return nil, fmt.Errorf("goal must not be blank")
 	}
+	var created beeminderDatapoint
+	err := client.do(ctx, http.MethodPost, client.goalPath(goal)+"/datapoints.json", dp.values(), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
 
-	postURL, err := url.Parse(client.serverURL)
+// updateDatapoint replaces the datapoint of goal with dp.ID with dp.
+func (client *beeminderClient) updateDatapoint(ctx context.Context, goal string, dp *beeminderDatapoint) error {
+	if goal == "" || dp.ID == "" {
+		return fmt.Errorf("goal and datapoint ID must not be blank")
+	}
+	path := client.goalPath(goal) + "/datapoints/" + url.PathEscape(dp.ID) + ".json"
+	return client.do(ctx, http.MethodPut, path, dp.values(), nil)
+}
+
+// deleteDatapoint removes the datapoint of goal with the given ID.
+func (client *beeminderClient) deleteDatapoint(ctx context.Context, goal, id string) error {
+	if goal == "" || id == "" {
+		return fmt.Errorf("goal and datapoint ID must not be blank")
+	}
+	path := client.goalPath(goal) + "/datapoints/" + url.PathEscape(id) + ".json"
+	return client.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+func (client *beeminderClient) goalPath(goal string) string {
+	return fmt.Sprintf("api/v1/users/%s/goals/%s", url.PathEscape(client.User), url.PathEscape(goal))
+}
+
+// do makes an authenticated request to the API, decoding the response into
+// out unless it is nil. Rate-limited requests are retried after the delay the
+// server asks for.
+func (client *beeminderClient) do(
+	ctx context.Context,
+	method string,
+	path string,
+	form url.Values,
+	out interface{},
+) error {
+	reqURL, err := url.Parse(client.serverURL)
 	if err != nil {
 		return fmt.Errorf("internal URL error: %w", err)
 	}
-	postURL.Path = fmt.Sprintf("api/v1/users/%s/goals/%s/datapoints.json",
-		client.User, goal)
+	reqURL.Path = path
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("auth_token", string(client.Token))
 
-	v := url.Values{}
-	v.Set("auth_token", string(client.Token))
-	v.Set("value", strconv.Itoa(count))
-	v.Set("comment", "via Gurnel at "+t.Format("15:04:05 MST"))
+	for attempt := 0; ; attempt++ {
+		var req *http.Request
+		if method == http.MethodGet || method == http.MethodDelete {
+			reqURL.RawQuery = form.Encode()
+			req, err = http.NewRequestWithContext(ctx, method, reqURL.String(), nil)
+		} else {
+			req, err = http.NewRequestWithContext(ctx, method, reqURL.String(),
+				strings.NewReader(form.Encode()))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+
+		resp, err := client.c.Do(req)
+		if err != nil {
+			// The error names the URL, which holds the auth token of GET and
+			// DELETE requests
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				urlErr.URL = redactToken(urlErr.URL)
+			}
+			return fmt.Errorf("making request: %w", err)
+		}
+		respData, readErr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if wait, ok := retryAfter(resp, attempt); ok && attempt < beeminderRetries {
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return fmt.Errorf("waiting to retry: %w", ctx.Err())
+			}
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			if readErr != nil || len(respData) == 0 {
+				respData = []byte("no further info")
+			}
+			return fmt.Errorf("server returned %s: %s", resp.Status, respData)
+		}
+		if readErr != nil {
+			return fmt.Errorf("reading response: %w", readErr)
+		}
+		if out == nil || len(respData) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(respData, out); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+		return nil
+	}
+}
 
-	resp, err := client.c.PostForm(postURL.String(), v)
+// redactToken returns rawURL with the value of its auth_token parameter
+// hidden.
+func redactToken(rawURL string) string {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("making request: %w", err)
+		return "(invalid URL)"
 	}
-	defer resp.Body.Close()
+	q := u.Query()
+	if _, ok := q["auth_token"]; !ok {
+		return rawURL
+	}
+	q.Set("auth_token", "REDACTED")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		respData, err := ioutil.ReadAll(resp.Body)
-		if err != nil || len(respData) == 0 {
-			respData = []byte("no further info")
-		}
-		return fmt.Errorf("server returned %s: %s", resp.Status, respData)
+// retryAfter reports whether resp asks for the request to be retried, and
+// how long to wait first. Requests that were rate-limited are retried after
+// the delay given by the Retry-After header, or else an exponential backoff.
+// Unavailable servers are retried only if they give a delay.
+func retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
 	}
-	return nil
+	var wait time.Duration
+	header := resp.Header.Get("Retry-After")
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		wait = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		wait = time.Until(t)
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		wait = time.Second << attempt
+	} else {
+		return 0, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > beeminderMaxWait {
+		wait = beeminderMaxWait
+	}
+	return wait, true
 }
@@ -2,13 +2,16 @@ package gurnel
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mikeraimondi/gurnel/internal/test"
 )
@@ -56,7 +59,7 @@ func TestBeeminderPostServerErrors(t *testing.T) {
 			now := (&test.FixedClock{}).Now()
 			result := make(chan error)
 			go func() {
-				result <- client.postDatapoint("foo", 1, now)
+				result <- client.postDatapoint(context.Background(), "foo", 1, "", now, now)
 			}()
 			err := <-result
 
@@ -145,7 +148,7 @@ func TestBeeminderPostParameters(t *testing.T) {
 			now := (&test.FixedClock{}).Now()
 			result := make(chan error)
 			go func() {
-				result <- client.postDatapoint(tt.goal, tt.count, now)
+				result <- client.postDatapoint(context.Background(), tt.goal, float64(tt.count), "", now, now)
 			}()
 			err := <-result
 			if !tt.valid {
@@ -251,7 +254,7 @@ func TestBeeminderClient(t *testing.T) {
 			}
 
 			now := (&test.FixedClock{}).Now()
-			err := client.postDatapoint("test", 10, now)
+			err := client.postDatapoint(context.Background(), "test", 10, "", now, now)
 			if tt.expectedErr == "" {
 				if err != nil {
 					t.Fatalf("expected no error. got %q", err)
@@ -264,3 +267,296 @@ func TestBeeminderClient(t *testing.T) {
 		})
 	}
 }
+
+func TestBeeminderErrorsHideToken(t *testing.T) {
+	token := "s3cret-token"
+	client := beeminderClient{
+		Token:     []byte(token),
+		User:      "alice",
+		serverURL: "http://test.com",
+		c:         http.Client{Transport: &testTransport{err: fmt.Errorf("test error")}},
+	}
+	ctx := context.Background()
+	now := (&test.FixedClock{}).Now()
+
+	tests := []struct {
+		desc string
+		call func() error
+	}{
+		{"with a GET request", func() error {
+			_, err := client.goal(ctx, "test")
+			return err
+		}},
+		{"with a DELETE request", func() error {
+			return client.deleteDatapoint(ctx, "test", "1")
+		}},
+		{"with a POST request", func() error {
+			return client.postDatapoint(ctx, "test", 10, "", now, now)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := tt.call()
+			test.CheckErr(t, "making request", err)
+			if strings.Contains(err.Error(), token) {
+				t.Fatalf("expected the token to be hidden. got %q", err)
+			}
+		})
+	}
+}
+
+func TestBeeminderRequests(t *testing.T) {
+	tests := []struct {
+		desc   string
+		call   func(*beeminderClient) (interface{}, error)
+		method string
+		path   string
+		form   map[string]string
+		resp   string
+		want   interface{}
+	}{
+		{
+			desc: "fetching a goal",
+			call: func(c *beeminderClient) (interface{}, error) {
+				return c.goal(context.Background(), "writing")
+			},
+			method: "GET",
+			path:   "/api/v1/users/alice/goals/writing.json",
+			resp:   `{"slug":"writing","safebuf":3,"rate":750,"runits":"d","losedate":1207958400,"limsum":"+750 due in 3 days"}`,
+			want: &beeminderGoal{
+				Slug:     "writing",
+				SafeBuf:  3,
+				Rate:     750,
+				RUnits:   "d",
+				LoseDate: 1207958400,
+				Limsum:   "+750 due in 3 days",
+			},
+		},
+		{
+			desc: "listing datapoints",
+			call: func(c *beeminderClient) (interface{}, error) {
+				return c.datapoints(context.Background(), "writing")
+			},
+			method: "GET",
+			path:   "/api/v1/users/alice/goals/writing/datapoints.json",
+			resp:   `[{"id":"a1","value":800,"daystamp":"20080412"}]`,
+			want:   []beeminderDatapoint{{ID: "a1", Value: 800, Daystamp: "20080412"}},
+		},
+		{
+			desc: "posting a datapoint",
+			call: func(c *beeminderClient) (interface{}, error) {
+				now := (&test.FixedClock{}).Now()
+				return nil, c.postDatapoint(context.Background(), "writing", 800, "hi", now, now)
+			},
+			method: "POST",
+			path:   "/api/v1/users/alice/goals/writing/datapoints.json",
+			form: map[string]string{
+				"auth_token": "s3cret",
+				"value":      "800",
+				"comment":    "hi",
+				"daystamp":   (&test.FixedClock{}).Now().Format(daystampFormat),
+				"requestid":  "gurnel-" + (&test.FixedClock{}).Now().Format(daystampFormat),
+			},
+		},
+		{
+			desc: "updating a datapoint",
+			call: func(c *beeminderClient) (interface{}, error) {
+				return nil, c.updateDatapoint(context.Background(), "writing",
+					&beeminderDatapoint{ID: "a1", Value: 900, Comment: "recounted"})
+			},
+			method: "PUT",
+			path:   "/api/v1/users/alice/goals/writing/datapoints/a1.json",
+			form:   map[string]string{"value": "900", "comment": "recounted"},
+		},
+		{
+			desc: "deleting a datapoint",
+			call: func(c *beeminderClient) (interface{}, error) {
+				return nil, c.deleteDatapoint(context.Background(), "writing", "a1")
+			},
+			method: "DELETE",
+			path:   "/api/v1/users/alice/goals/writing/datapoints/a1.json",
+			form:   map[string]string{"auth_token": "s3cret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tt.method {
+					t.Errorf("wrong method. expected %s. got %s", tt.method, r.Method)
+				}
+				if r.URL.Path != tt.path {
+					t.Errorf("wrong path. expected %s. got %s", tt.path, r.URL.Path)
+				}
+				for key, want := range tt.form {
+					if got := r.FormValue(key); got != want {
+						t.Errorf("wrong %s. expected %q. got %q", key, want, got)
+					}
+				}
+				w.Write([]byte(tt.resp))
+			})
+			server := httptest.NewTLSServer(handler)
+			defer server.Close()
+
+			client := beeminderClient{
+				Token:     []byte("s3cret"),
+				User:      "alice",
+				c:         *server.Client(),
+				serverURL: server.URL,
+			}
+			got, err := tt.call(&client)
+			if err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrong result. expected %+v. got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBeeminderRetryAfter(t *testing.T) {
+	tests := []struct {
+		desc       string
+		retryAfter string
+		failures   int
+		timeout    time.Duration
+		err        string
+		requests   int
+	}{
+		{
+			desc:       "with a rate limit that clears",
+			retryAfter: "0",
+			failures:   2,
+			requests:   3,
+		},
+		{
+			desc:       "with a rate limit that doesn't clear",
+			retryAfter: "0",
+			failures:   10,
+			requests:   beeminderRetries + 1,
+			err:        "429",
+		},
+		{
+			desc:       "with a wait longer than the deadline",
+			retryAfter: "30",
+			failures:   1,
+			timeout:    50 * time.Millisecond,
+			requests:   1,
+			err:        "waiting to retry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var requests int
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failures {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte(`{"slug":"writing"}`))
+			})
+			server := httptest.NewTLSServer(handler)
+			defer server.Close()
+
+			client := beeminderClient{
+				Token:     []byte("s3cret"),
+				User:      "alice",
+				c:         *server.Client(),
+				serverURL: server.URL,
+			}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			_, err := client.goal(ctx, "writing")
+			test.CheckErr(t, tt.err, err)
+			if requests != tt.requests {
+				t.Fatalf("expected %d requests. got %d", tt.requests, requests)
+			}
+		})
+	}
+}
+
+func TestReportEntry(t *testing.T) {
+	tests := []struct {
+		desc     string
+		goals    []BeeminderGoalConfig
+		after    time.Duration
+		posts    map[string]string
+		daystamp string
+		err      string
+	}{
+		{
+			desc:  "with the default metric and comment",
+			goals: []BeeminderGoalConfig{{Slug: "writing"}},
+			posts: map[string]string{"writing": "3 via Gurnel at 16:00:00 UTC"},
+		},
+		{
+			desc: "with several goals",
+			goals: []BeeminderGoalConfig{
+				{Slug: "minutes", Metric: "minutes", Comment: "{{.Minutes}}m, {{.Words}} words"},
+				{Slug: "daily", Metric: "binary", Comment: "{{.Date.Format \"Jan 2\"}}"},
+				{Slug: "mood", Metric: "mood", Comment: "mood {{.Value}}"},
+			},
+			posts: map[string]string{
+				"minutes": "25 25m, 3 words",
+				"daily":   "1 Apr 12",
+				"mood":    "4 mood 4",
+			},
+		},
+		{
+			desc:     "after midnight",
+			goals:    []BeeminderGoalConfig{{Slug: "writing"}},
+			after:    8*time.Hour + 30*time.Minute,
+			posts:    map[string]string{"writing": "3 via Gurnel at 00:30:00 UTC"},
+			daystamp: "20080412",
+		},
+		{
+			desc:  "with an unknown metric",
+			goals: []BeeminderGoalConfig{{Slug: "writing", Metric: "sentences"}},
+			err:   "unknown metric",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			p := writeTestEntries(t, dir, "one two three")[0]
+			p.AverageMood = 4
+			p.TimeSpent = Duration(25*time.Minute + 10*time.Second)
+
+			posts := make(map[string]string)
+			var daystamp string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				goal := strings.Split(r.URL.Path, "/")[6]
+				posts[goal] = r.FormValue("value") + " " + r.FormValue("comment")
+				daystamp = r.FormValue("daystamp")
+			})
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			client := beeminderClient{
+				Token:     []byte("s3cret"),
+				User:      "alice",
+				c:         *server.Client(),
+				serverURL: server.URL,
+			}
+
+			now := (&test.FixedClock{}).Now().Add(tt.after)
+			err := reportEntry(context.Background(), &client, tt.goals, p, now)
+			test.CheckErr(t, tt.err, err)
+			if tt.err == "" && !reflect.DeepEqual(posts, tt.posts) {
+				t.Fatalf("wrong datapoints. expected %v. got %v", tt.posts, posts)
+			}
+			if tt.daystamp != "" && daystamp != tt.daystamp {
+				t.Fatalf("wrong daystamp. expected %s. got %s", tt.daystamp, daystamp)
+			}
+		})
+	}
+}
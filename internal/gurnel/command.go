@@ -6,6 +6,8 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 )
@@ -74,7 +76,7 @@ Usage:
 
 The commands are:
 {{range .}}
-  {{.Name | printf "%-11s"}} {{.ShortHelp}}{{end}}
+  {{.Name | printf "%-15s"}} {{.ShortHelp}}{{end}}
 Use "gurnel help [command]" for more information about a command.
 `
 	tmpl(bw, usageTemplate, commands)
@@ -111,3 +113,17 @@ func tmpl(w io.Writer, text string, data interface{}) {
 		panic(err)
 	}
 }
+
+// journalDir returns the root of the journal: the working directory, with
+// any symlinks resolved.
+func journalDir() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", errors.New("getting working directory " + err.Error())
+	}
+	wd, err = filepath.EvalSymlinks(wd)
+	if err != nil {
+		return "", errors.New("evaluating symlinks " + err.Error())
+	}
+	return wd, nil
+}
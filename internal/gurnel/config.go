@@ -3,6 +3,7 @@ package gurnel
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -21,12 +22,28 @@ type Config struct {
 	BeeminderEnabled   bool
 	BeeminderUser      string
 	BeeminderTokenFile string
+	BeeminderToken     CredentialConfig
 	BeeminderGoal      string
+	BeeminderGoals     []BeeminderGoalConfig
 	MinimumWordCount   int
 	Editor             string
+	Backups            int
+	IdleTimeout        Duration
+	Layout             Layout
+	ExtractTags        bool
+	StoreSentiment     bool
+	OnThisDay          bool
+	Remind             RemindConfig
+	TimeZone           string
+	DayRolloverHour    int
+	Publish            PublishConfig
+	Webhooks           []WebhookConfig
+	Hooks              HooksConfig
+	Encryption         EncryptionConfig
 	dp                 dirProvider
 	subcommands        []subcommand
 	clock              clock
+	beeminderURL       string
 }
 
 type defaultDirProvider struct{}
@@ -60,7 +77,45 @@ func (c *Config) Load(path ...string) error {
 		}
 		return fmt.Errorf("opening file: %w", err)
 	}
-	return json.Unmarshal(configData, c)
+	if err := json.Unmarshal(configData, c); err != nil {
+		return err
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			return fmt.Errorf("invalid TimeZone: %w", err)
+		}
+	}
+	if c.DayRolloverHour < 0 || c.DayRolloverHour > 23 {
+		return fmt.Errorf("invalid DayRolloverHour %d. It must be from 0 to 23", c.DayRolloverHour)
+	}
+	return nil
+}
+
+// now returns the current time, in TimeZone if one is configured.
+func (c *Config) now() time.Time {
+	t := c.clock.Now()
+	if c.TimeZone != "" {
+		if loc, err := time.LoadLocation(c.TimeZone); err == nil {
+			t = t.In(loc)
+		}
+	}
+	return t
+}
+
+// today returns the current time for the purpose of choosing an entry: now,
+// less DayRolloverHour, so that writing after midnight but before the
+// rollover counts toward the day before.
+func (c *Config) today() time.Time {
+	return c.now().Add(-time.Duration(c.DayRolloverHour) * time.Hour)
+}
+
+// zoneName returns the time zone recorded in entries.
+func (c *Config) zoneName() string {
+	if c.TimeZone != "" {
+		return c.TimeZone
+	}
+	name, _ := c.now().Zone()
+	return name
 }
 
 func (c *Config) getConfigDir() (string, error) {
@@ -75,11 +130,56 @@ func (c *Config) getConfigDir() (string, error) {
 	return dir, nil
 }
 
+// beeminderToken returns the Beeminder auth token from the source configured
+// in BeeminderToken, or else from BeeminderTokenFile.
+func (c *Config) beeminderToken(w io.Writer) ([]byte, error) {
+	cc := c.BeeminderToken
+	if cc.Source == "" {
+		cc = CredentialConfig{Source: "file", File: c.BeeminderTokenFile}
+	}
+	return cc.credential(w)
+}
+
+// beeminderClient returns a client for the configured Beeminder user.
+func (c *Config) beeminderClient(w io.Writer) (*beeminderClient, error) {
+	token, err := c.beeminderToken(w)
+	if err != nil {
+		return nil, fmt.Errorf("reading token: %w", err)
+	}
+	client, err := newBeeminderClient(c.BeeminderUser, token)
+	if err != nil {
+		return nil, fmt.Errorf("setting up client: %w", err)
+	}
+	if c.beeminderURL != "" {
+		client.serverURL = c.beeminderURL
+	}
+	return client, nil
+}
+
+// beeminderGoals returns the goals entries are reported to: BeeminderGoals,
+// or else BeeminderGoal with the words metric.
+func (c *Config) beeminderGoals() []BeeminderGoalConfig {
+	if len(c.BeeminderGoals) == 0 && c.BeeminderGoal != "" {
+		return []BeeminderGoalConfig{{Slug: c.BeeminderGoal}}
+	}
+	return c.BeeminderGoals
+}
+
 func (c *Config) setupSubcommands() {
 	if len(c.subcommands) == 0 {
 		c.subcommands = []subcommand{
 			&startCmd{},
 			&statsCmd{},
+			&migrateLayoutCmd{},
+			&publishCmd{},
+			&exportCmd{},
+			&importCmd{},
+			&statusCmd{},
+			&webhookCmd{},
+			&tagsCmd{},
+			&onThisDayCmd{},
+			&reviewCmd{},
+			&remindCmd{},
 		}
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
 )
 
 type testDirProvider struct {
@@ -109,3 +111,83 @@ func TestGetConfigDir(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		desc string
+		conf string
+		err  string
+	}{
+		{
+			desc: "with an unknown time zone",
+			conf: `{"TimeZone": "Mars/Olympus_Mons"}`,
+			err:  "invalid TimeZone",
+		},
+		{
+			desc: "with a rollover hour out of range",
+			conf: `{"DayRolloverHour": 24}`,
+			err:  "invalid DayRolloverHour",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			if err := ioutil.WriteFile(filepath.Join(dir, "conf.json"), []byte(tC.conf), 0600); err != nil {
+				t.Fatalf("writing config: %s", err)
+			}
+			c := Config{dp: &testDirProvider{configDir: dir}}
+			test.CheckErr(t, tC.err, c.Load("conf.json"))
+		})
+	}
+}
+
+func TestConfigToday(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		timeZone string
+		rollover int
+		date     string
+		zone     string
+	}{
+		{
+			desc: "with the defaults",
+			date: "2008-04-12",
+			zone: "UTC",
+		},
+		{
+			desc:     "with a time zone past midnight",
+			timeZone: "Asia/Tokyo",
+			date:     "2008-04-13",
+			zone:     "Asia/Tokyo",
+		},
+		{
+			desc:     "with a time zone before the rollover",
+			timeZone: "Asia/Tokyo",
+			rollover: 4,
+			date:     "2008-04-12",
+			zone:     "Asia/Tokyo",
+		},
+		{
+			desc:     "with a rollover after the current hour",
+			rollover: 17,
+			date:     "2008-04-11",
+			zone:     "UTC",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			c := Config{
+				TimeZone:        tC.timeZone,
+				DayRolloverHour: tC.rollover,
+				clock:           &test.FixedClock{},
+			}
+			if got := c.today().Format(dateArgFormat); got != tC.date {
+				t.Fatalf("expected %s. got %s", tC.date, got)
+			}
+			if got := c.zoneName(); got != tC.zone {
+				t.Fatalf("expected zone %s. got %s", tC.zone, got)
+			}
+		})
+	}
+}
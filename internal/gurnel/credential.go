@@ -0,0 +1,156 @@
+package gurnel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+)
+
+// CredentialConfig says where a secret, such as an API token, is kept.
+type CredentialConfig struct {
+	// Source is one of "file", "env", "command", or "secret-service".
+	Source string
+	// File names the file holding the secret, for the "file" source. It
+	// must not be readable by other users.
+	File string
+	// Env names the environment variable holding the secret, for the "env"
+	// source.
+	Env string
+	// Command is run by the shell to print the secret, for the "command"
+	// source. For example, "pass show beeminder".
+	Command string
+	// Attributes identify the secret in the freedesktop Secret Service, for
+	// the "secret-service" source, as given to secret-tool lookup.
+	Attributes map[string]string
+}
+
+// credentialProvider supplies a secret. Any errors reported by a helper
+// program are written to w.
+type credentialProvider interface {
+	credential(w io.Writer) ([]byte, error)
+}
+
+// provider returns the credentialProvider for cc's source.
+func (cc *CredentialConfig) provider() (credentialProvider, error) {
+	switch cc.Source {
+	case "file":
+		return fileCredential(cc.File), nil
+	case "env":
+		return envCredential(cc.Env), nil
+	case "command":
+		return commandCredential(cc.Command), nil
+	case "secret-service":
+		return secretServiceCredential(cc.Attributes), nil
+	case "":
+		return nil, errors.New("no credential source configured")
+	default:
+		return nil, fmt.Errorf("unknown credential source %q", cc.Source)
+	}
+}
+
+// credential returns the secret from cc's source, with surrounding
+// whitespace removed. Any errors reported by a helper program are written
+// to w.
+func (cc *CredentialConfig) credential(w io.Writer) ([]byte, error) {
+	p, err := cc.provider()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := p.credential(w)
+	if err != nil {
+		return nil, err
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%s credential is blank", cc.Source)
+	}
+	return secret, nil
+}
+
+// fileCredential reads a secret from the file it names.
+type fileCredential string
+
+func (path fileCredential) credential(io.Writer) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("no credential file configured")
+	}
+	info, err := os.Stat(string(path))
+	if err != nil {
+		return nil, err
+	}
+	// Windows doesn't have Unix permissions to check
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0004 != 0 {
+		return nil, fmt.Errorf("%s is readable by other users. Run 'chmod o-r %s'", path, path)
+	}
+	return ioutil.ReadFile(string(path))
+}
+
+// envCredential reads a secret from the environment variable it names.
+type envCredential string
+
+func (name envCredential) credential(io.Writer) ([]byte, error) {
+	if name == "" {
+		return nil, errors.New("no credential environment variable configured")
+	}
+	secret, ok := os.LookupEnv(string(name))
+	if !ok {
+		return nil, fmt.Errorf("$%s is not set", name)
+	}
+	return []byte(secret), nil
+}
+
+// commandCredential runs a helper command, such as a password manager, that
+// prints a secret.
+type commandCredential string
+
+func (command commandCredential) credential(w io.Writer) ([]byte, error) {
+	if command == "" {
+		return nil, errors.New("no credential command configured")
+	}
+	// #nosec
+	cmd := exec.Command("sh", "-c", string(command))
+	cmd.Stderr = w
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running credential command: %w", err)
+	}
+	return out, nil
+}
+
+// secretServiceCredential looks up a secret by its attributes in the
+// freedesktop Secret Service (such as GNOME Keyring or KWallet), using
+// libsecret's secret-tool to talk to it over D-Bus.
+type secretServiceCredential map[string]string
+
+func (attrs secretServiceCredential) credential(w io.Writer) ([]byte, error) {
+	if len(attrs) == 0 {
+		return nil, errors.New("no Secret Service attributes configured")
+	}
+	tool, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, errors.New("the Secret Service is unavailable: secret-tool is not installed")
+	}
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	args := []string{"lookup"}
+	for _, key := range keys {
+		args = append(args, key, attrs[key])
+	}
+	// #nosec
+	cmd := exec.Command(tool, args...)
+	cmd.Stderr = w
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("looking up secret: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package gurnel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestCredential(t *testing.T) {
+	testCases := []struct {
+		desc string
+		perm os.FileMode
+		env  map[string]string
+		conf CredentialConfig
+		want string
+		out  string
+		err  string
+	}{
+		{
+			desc: "with a private file",
+			perm: 0600,
+			conf: CredentialConfig{Source: "file", File: "token"},
+			want: "s3cret",
+		},
+		{
+			desc: "with a world-readable file",
+			perm: 0644,
+			conf: CredentialConfig{Source: "file", File: "token"},
+			err:  "readable by other users",
+		},
+		{
+			desc: "with an environment variable",
+			env:  map[string]string{"GURNEL_TEST_TOKEN": " s3cret\n"},
+			conf: CredentialConfig{Source: "env", Env: "GURNEL_TEST_TOKEN"},
+			want: "s3cret",
+		},
+		{
+			desc: "with an unset environment variable",
+			conf: CredentialConfig{Source: "env", Env: "GURNEL_TEST_UNSET"},
+			err:  "is not set",
+		},
+		{
+			desc: "with a helper command",
+			conf: CredentialConfig{Source: "command", Command: "echo s3cret"},
+			want: "s3cret",
+		},
+		{
+			desc: "with a helper command that warns",
+			conf: CredentialConfig{Source: "command", Command: "echo cache stale >&2; echo s3cret"},
+			want: "s3cret",
+			out:  "cache stale",
+		},
+		{
+			desc: "with a failing helper command",
+			conf: CredentialConfig{Source: "command", Command: "exit 1"},
+			err:  "running credential command",
+		},
+		{
+			desc: "with a blank secret",
+			conf: CredentialConfig{Source: "command", Command: "true"},
+			err:  "blank",
+		},
+		{
+			desc: "with no Secret Service attributes",
+			conf: CredentialConfig{Source: "secret-service"},
+			err:  "no Secret Service attributes",
+		},
+		{
+			desc: "with an unknown source",
+			conf: CredentialConfig{Source: "carrier-pigeon"},
+			err:  "unknown credential source",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			if tC.conf.File != "" {
+				tC.conf.File = filepath.Join(dir, tC.conf.File)
+				if err := ioutil.WriteFile(tC.conf.File, []byte("s3cret\n"), tC.perm); err != nil {
+					t.Fatalf("writing token: %s", err)
+				}
+				// Not subject to the umask
+				if err := os.Chmod(tC.conf.File, tC.perm); err != nil {
+					t.Fatalf("setting permissions: %s", err)
+				}
+			}
+			for name, value := range tC.env {
+				os.Setenv(name, value)
+				defer os.Unsetenv(name)
+			}
+
+			out := bytes.Buffer{}
+			secret, err := tC.conf.credential(&out)
+			test.CheckErr(t, tC.err, err)
+			if string(secret) != tC.want {
+				t.Fatalf("expected secret %q. got %q", tC.want, secret)
+			}
+			if out.String() != "" || tC.out != "" {
+				test.CheckOutput(t, []string{tC.out}, out.String())
+			}
+		})
+	}
+}
+
+func TestBeeminderTokenDefault(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(path, []byte("s3cret"), 0600); err != nil {
+		t.Fatalf("writing token: %s", err)
+	}
+
+	conf := Config{BeeminderTokenFile: path}
+	token, err := conf.beeminderToken(ioutil.Discard)
+	if err != nil || string(token) != "s3cret" {
+		t.Fatalf("expected token from BeeminderTokenFile. got %q, %v", token, err)
+	}
+}
@@ -0,0 +1,322 @@
+package gurnel
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// keySize is the size of the key bodies are encrypted with.
+	keySize = 32
+	// keyFile holds a journal's keyParams, in its config directory.
+	keyFile = "encryption.json"
+	// keyCheck is sealed with the key when it is first derived, so that a
+	// wrong passphrase can be told apart from a damaged entry.
+	keyCheck = "gurnel"
+	// encryptedHeader begins an encrypted body. The base64 encoding of the
+	// nonce and sealed box follows.
+	encryptedHeader = "<!-- gurnel:encrypted -->\n"
+	// passphraseEnv is the environment variable the passphrase is read from
+	// if no other source is configured.
+	passphraseEnv = "GURNEL_PASSPHRASE"
+)
+
+// EncryptionConfig configures encryption of entries' bodies at rest. Bodies
+// are sealed with NaCl secretbox, using a key derived from a passphrase with
+// scrypt. Frontmatter is left in plain text.
+type EncryptionConfig struct {
+	Enabled bool
+	// Passphrase says where the passphrase is kept. It defaults to
+	// $GURNEL_PASSPHRASE.
+	Passphrase CredentialConfig
+	// PassphraseCommand is run by the shell to print the passphrase, if
+	// Passphrase isn't set. It is read from configs written before
+	// Passphrase was added.
+	PassphraseCommand string
+}
+
+func (ec *EncryptionConfig) passphrase(w io.Writer) ([]byte, error) {
+	cc := ec.Passphrase
+	if cc.Source == "" && ec.PassphraseCommand != "" {
+		// Keys were derived from all the command printed but the final line
+		// break, so the passphrase must be read the same way
+		out, err := commandCredential(ec.PassphraseCommand).credential(w)
+		if err != nil {
+			return nil, fmt.Errorf("getting passphrase: %w", err)
+		}
+		return bytes.TrimRight(out, "\r\n"), nil
+	}
+	if cc.Source == "" {
+		cc = CredentialConfig{Source: "env", Env: passphraseEnv}
+	}
+	pass, err := cc.credential(w)
+	if err != nil {
+		return nil, fmt.Errorf("getting passphrase: %w", err)
+	}
+	return pass, nil
+}
+
+// keyParams are the salt and scrypt parameters a journal's key is derived
+// with.
+type keyParams struct {
+	Salt    []byte
+	N, R, P int
+	Check   []byte
+}
+
+// journalKey derives the key for the journal rooted at root from the
+// configured passphrase. The first time, a random salt is chosen and saved,
+// and the file it's saved in is added to git, so that clones of the journal
+// can derive the same key.
+func journalKey(w io.Writer, root string, ec *EncryptionConfig) (*[keySize]byte, error) {
+	pass, err := ec.passphrase(w)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(root, journalConfigDir, keyFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// A new salt would give a key that can't open the existing entries
+		if found, err := hasEncryptedEntries(root); err != nil || found {
+			if err == nil {
+				err = fmt.Errorf("missing %s, which the journal's entries were encrypted with. "+
+					"Copy it from where the journal was first encrypted", path)
+			}
+			return nil, err
+		}
+		key, err := newJournalKey(path, pass)
+		if err != nil {
+			return nil, err
+		}
+		if err := git(root, "add", path); err != nil {
+			fmt.Fprintf(w, "Back up %s, or entries can't be decrypted: %s\n", path, err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading key parameters: %w", err)
+	}
+
+	var params keyParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	key, err := deriveKey(pass, &params)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := openBox(key, params.Check); err != nil || string(check) != keyCheck {
+		return nil, errors.New("wrong passphrase")
+	}
+	return key, nil
+}
+
+// hasEncryptedEntries reports whether any file in the journal rooted at root
+// has an encrypted body.
+func hasEncryptedEntries(root string) (bool, error) {
+	found := false
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || found {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		found = bytes.Contains(data, []byte(encryptedHeader))
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("looking for encrypted entries: %w", err)
+	}
+	return found, nil
+}
+
+func newJournalKey(path string, pass []byte) (*[keySize]byte, error) {
+	params := keyParams{Salt: make([]byte, 16), N: 1 << 15, R: 8, P: 1}
+	if _, err := rand.Read(params.Salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(pass, &params)
+	if err != nil {
+		return nil, err
+	}
+	if params.Check, err = sealBox(key, []byte(keyCheck)); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(&params, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return nil, fmt.Errorf("saving key parameters: %w", err)
+	}
+	return key, nil
+}
+
+func deriveKey(pass []byte, params *keyParams) (*[keySize]byte, error) {
+	k, err := scrypt.Key(pass, params.Salt, params.N, params.R, params.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	var key [keySize]byte
+	copy(key[:], k)
+	return &key, nil
+}
+
+// sealBox encrypts msg with key, prefixed by a random nonce.
+func sealBox(key *[keySize]byte, msg []byte) ([]byte, error) {
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+	return secretbox.Seal(nonce[:], msg, &nonce, key), nil
+}
+
+// openBox decrypts box, as sealed by sealBox.
+func openBox(key *[keySize]byte, box []byte) ([]byte, error) {
+	var nonce [24]byte
+	if len(box) < len(nonce) {
+		return nil, errors.New("encrypted data is too short")
+	}
+	copy(nonce[:], box)
+	msg, ok := secretbox.Open(nil, box[len(nonce):], &nonce, key)
+	if !ok {
+		return nil, errors.New("decryption failed")
+	}
+	return msg, nil
+}
+
+func isEncrypted(body []byte) bool {
+	return bytes.HasPrefix(body, []byte(encryptedHeader))
+}
+
+// encryptBody returns body sealed with key, as text that can follow an
+// entry's frontmatter.
+func encryptBody(key *[keySize]byte, body []byte) ([]byte, error) {
+	box, err := sealBox(key, body)
+	if err != nil {
+		return nil, err
+	}
+	enc := base64.StdEncoding.EncodeToString(box)
+	out := []byte(encryptedHeader)
+	for len(enc) > 76 {
+		out = append(append(out, enc[:76]...), '\n')
+		enc = enc[76:]
+	}
+	return append(append(out, enc...), '\n'), nil
+}
+
+// decryptBody reverses encryptBody. A body that isn't encrypted is returned
+// unchanged, so that entries written before encryption was enabled can still
+// be read.
+func decryptBody(key *[keySize]byte, body []byte) ([]byte, error) {
+	if !isEncrypted(body) {
+		return body, nil
+	}
+	if key == nil {
+		return nil, errors.New("entry is encrypted. Set Encryption.Enabled in your config")
+	}
+	enc := bytes.Join(bytes.Fields(body[len(encryptedHeader):]), nil)
+	box := make([]byte, base64.StdEncoding.DecodedLen(len(enc)))
+	n, err := base64.StdEncoding.Decode(box, enc)
+	if err != nil {
+		return nil, fmt.Errorf("decoding encrypted body: %w", err)
+	}
+	return openBox(key, box[:n])
+}
+
+// decryptedCopy writes p, unencrypted, into a new directory readable only by
+// the user, so that it can be edited. wipe overwrites and removes the copy.
+// Once editing is done, p.adopt takes back the copy's contents.
+func (p *Entry) decryptedCopy() (c *Entry, wipe func(), err error) {
+	dir, err := privateTempDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	wipe = func() { wipeDir(dir) }
+
+	data, err := p.marshal(nil)
+	if err != nil {
+		wipe()
+		return nil, nil, err
+	}
+	c = &Entry{}
+	*c = *p
+	c.Path = filepath.Join(dir, filepath.Base(p.Path))
+	c.root = dir
+	c.key = nil
+	c.Backups = 0
+	if err := ioutil.WriteFile(c.Path, data, 0600); err != nil {
+		wipe()
+		return nil, nil, fmt.Errorf("writing decrypted copy: %w", err)
+	}
+	c.sum = sha256.Sum256(data)
+	return c, wipe, nil
+}
+
+// adopt replaces the contents of p with those of c, a copy made by
+// p.decryptedCopy.
+func (p *Entry) adopt(c *Entry) {
+	path, root, key, backups := p.Path, p.root, p.key, p.Backups
+	*p = *c
+	p.Path, p.root, p.key, p.Backups = path, root, key, backups
+}
+
+// privateTempDir creates a new temporary directory accessible only by the
+// user.
+func privateTempDir() (string, error) {
+	dir, err := ioutil.TempDir("", "gurnel-")
+	if err != nil {
+		return "", fmt.Errorf("creating temp directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("setting permissions: %w", err)
+	}
+	return dir, nil
+}
+
+// wipeDir overwrites the files in dir with zeros before removing it, so that
+// plain text isn't left behind. Editors' swap and backup files are wiped
+// along with everything else.
+func wipeDir(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		os.Chmod(path, 0600)
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		if _, err := f.Write(make([]byte, info.Size())); err == nil {
+			f.Sync()
+		}
+		return nil
+	})
+	os.RemoveAll(dir)
+}
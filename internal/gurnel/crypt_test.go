@@ -0,0 +1,219 @@
+package gurnel
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestJournalKey(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		first  string
+		second string
+		err    string
+	}{
+		{
+			desc:   "with the same passphrase",
+			first:  "correct horse",
+			second: "correct horse",
+		},
+		{
+			desc:   "with a different passphrase",
+			first:  "correct horse",
+			second: "battery staple",
+			err:    "wrong passphrase",
+		},
+		{
+			desc: "with no passphrase",
+			err:  "getting passphrase",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			defer os.Unsetenv(passphraseEnv)
+
+			var first *[keySize]byte
+			if tC.first != "" {
+				os.Setenv(passphraseEnv, tC.first)
+				var err error
+				if first, err = journalKey(ioutil.Discard, dir, &EncryptionConfig{Enabled: true}); err != nil {
+					t.Fatalf("deriving first key: %s", err)
+				}
+			}
+			os.Setenv(passphraseEnv, tC.second)
+			second, err := journalKey(ioutil.Discard, dir, &EncryptionConfig{Enabled: true})
+			test.CheckErr(t, tC.err, err)
+			if err == nil && *first != *second {
+				t.Fatal("expected the same key from the same passphrase")
+			}
+		})
+	}
+}
+
+func TestJournalKeyParams(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		entry string
+		git   bool
+		err   string
+	}{
+		{
+			desc: "in a git repository",
+			git:  true,
+		},
+		{
+			desc:  "with entries encrypted elsewhere",
+			entry: "---\nlowmood: 1\n---\n" + encryptedHeader + "AAAA\n",
+			err:   "missing",
+		},
+		{
+			desc:  "with entries in plain text",
+			entry: "---\nlowmood: 1\n---\nfoo bar\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			os.Setenv(passphraseEnv, "correct horse")
+			defer os.Unsetenv(passphraseEnv)
+			if tC.git {
+				if err := git(dir, "init", "-q"); err != nil {
+					t.Fatalf("initializing repository: %s", err)
+				}
+			}
+			if tC.entry != "" {
+				if err := ioutil.WriteFile(filepath.Join(dir, "2008-04-12.md"), []byte(tC.entry), 0600); err != nil {
+					t.Fatalf("writing entry: %s", err)
+				}
+			}
+
+			_, err := journalKey(ioutil.Discard, dir, &EncryptionConfig{Enabled: true})
+			test.CheckErr(t, tC.err, err)
+			if tC.git {
+				out, err := gitOutput(dir, "diff", "--cached", "--name-only")
+				if err != nil {
+					t.Fatalf("listing staged files: %s", err)
+				}
+				test.CheckOutput(t, []string{keyFile}, out)
+			}
+		})
+	}
+}
+
+func TestPassphraseConfig(t *testing.T) {
+	testCases := []struct {
+		desc string
+		json string
+		want string
+	}{
+		{
+			desc: "with a passphrase source",
+			json: `{"Passphrase": {"Source": "command", "Command": "echo correct horse"}}`,
+			want: "correct horse",
+		},
+		{
+			desc: "with a passphrase command from an older config",
+			json: `{"PassphraseCommand": "echo ' correct horse'"}`,
+			want: " correct horse",
+		},
+		{
+			desc: "with both",
+			json: `{"Passphrase": {"Source": "command", "Command": "echo battery staple"}, "PassphraseCommand": "echo correct horse"}`,
+			want: "battery staple",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var ec EncryptionConfig
+			if err := json.Unmarshal([]byte(tC.json), &ec); err != nil {
+				t.Fatalf("parsing config: %s", err)
+			}
+			pass, err := ec.passphrase(ioutil.Discard)
+			if err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			if string(pass) != tC.want {
+				t.Fatalf("wrong passphrase. expected %q. got %q", tC.want, pass)
+			}
+		})
+	}
+}
+
+func TestEncryptedEntry(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	var key [keySize]byte
+	copy(key[:], "0123456789abcdef0123456789abcdef")
+	j := &journal{root: dir, layout: DefaultLayout, key: &key}
+
+	p, err := j.newEntry((&test.FixedClock{}).Now())
+	if err != nil {
+		t.Fatalf("creating entry: %s", err)
+	}
+	p.Body = []byte("the secret garden\n")
+	p.AverageMood = 4
+	if err := p.Save(); err != nil {
+		t.Fatalf("saving entry: %s", err)
+	}
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		t.Fatalf("reading entry: %s", err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("expected body to be encrypted. got %q", data)
+	}
+
+	loaded := j.entry(p.Path)
+	if _, err := loaded.Load(); err != nil {
+		t.Fatalf("loading entry: %s", err)
+	}
+	if string(loaded.Body) != "the secret garden\n" || loaded.AverageMood != 4 {
+		t.Fatalf("wrong entry after loading. got body %q and mood %d", loaded.Body, loaded.AverageMood)
+	}
+
+	_, err = (&journal{root: dir, layout: DefaultLayout}).entry(p.Path).Load()
+	test.CheckErr(t, "entry is encrypted", err)
+
+	// Editing happens on a decrypted copy
+	c, wipe, err := loaded.decryptedCopy()
+	if err != nil {
+		t.Fatalf("copying entry: %s", err)
+	}
+	info, err := os.Stat(c.Path)
+	if err != nil {
+		t.Fatalf("expected a decrypted copy: %s", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected copy to be private. got mode %v", info.Mode())
+	}
+	if err := ioutil.WriteFile(c.Path, []byte("---\naveragemood: 2\n---\nthe secret is out\n"), 0600); err != nil {
+		t.Fatalf("editing copy: %s", err)
+	}
+	if modified, err := c.Load(); err != nil || !modified {
+		t.Fatalf("expected copy to be modified. got %v, %v", modified, err)
+	}
+	wipe()
+	if _, err := os.Stat(c.Path); !os.IsNotExist(err) {
+		t.Fatalf("expected copy to be wiped. got %v", err)
+	}
+
+	loaded.adopt(c)
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("saving entry: %s", err)
+	}
+	if data, _ := ioutil.ReadFile(p.Path); bytes.Contains(data, []byte("secret")) {
+		t.Fatalf("expected body to be encrypted. got %q", data)
+	}
+	if _, err := p.Load(); err != nil || string(p.Body) != "the secret is out\n" || p.AverageMood != 2 {
+		t.Fatalf("wrong entry after editing. got body %q, mood %d, and error %v", p.Body, p.AverageMood, err)
+	}
+}
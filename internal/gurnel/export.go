@@ -0,0 +1,169 @@
+package gurnel
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+type exportCmd struct {
+	from string
+	to   string
+}
+
+func (*exportCmd) Name() string      { return "export" }
+func (*exportCmd) ShortHelp() string { return "Export the journal to other formats" }
+
+func (c *exportCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.StringVar(&c.from, "from", "", "export entries from this date (YYYY-MM-DD)")
+	fs.StringVar(&c.to, "to", "", "export entries up to and including this date (YYYY-MM-DD)")
+	return fs
+}
+
+func (*exportCmd) LongHelp() string {
+	return `Exports the journal in one of these formats:
+
+  html <outdir>  a static website with an index by year and month, a page per
+                 entry, a tag index, and charts of moods and word counts
+  epub <file>    an e-book with a chapter per month
+  print <file>   a single HTML page with a print stylesheet, for converting
+                 to PDF
+  jsonl [file]   one JSON object per line for each entry, with its date,
+                 body, word count, and frontmatter. Defaults to stdout
+
+Use -from and -to to export only the entries in a range of dates.
+
+To change how the website looks, put templates named base.html, index.html,
+entry.html, or tags.html in .gurnel/templates/html, redefining the templates
+of the same name.`
+}
+
+func (c *exportCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	if len(args) < 1 {
+		return errors.New("no export format given. Run 'gurnel help export' for usage")
+	}
+	var r dateRange
+	var err error
+	if c.from != "" {
+		if r.from, err = parseDateArg(c.from, conf.today()); err != nil {
+			return err
+		}
+	}
+	if c.to != "" {
+		if r.to, err = parseDateArg(c.to, conf.today()); err != nil {
+			return err
+		}
+	}
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+
+	format, args := args[0], args[1:]
+	if format == "jsonl" && (len(args) == 0 || args[0] == "-") {
+		_, err := exportJSONL(j, r, w)
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("exactly one output path must be given")
+	}
+	out := args[0]
+
+	var count int
+	switch format {
+	case "html":
+		// The site's indexes need every entry, so they're all kept until
+		// it's rendered
+		var entries []*Entry
+		err = streamEntries(j, r, func(p *Entry) error {
+			entries = append(entries, p)
+			return nil
+		})
+		if err == nil {
+			count = len(entries)
+			err = exportHTML(j.root, entries, out)
+		}
+	case "epub", "print", "jsonl":
+		count, err = exportFile(out, func(f io.Writer) (int, error) {
+			switch format {
+			case "epub":
+				return exportEPUB(j, r, f, conf.now())
+			case "print":
+				return exportPrint(j, r, f)
+			default:
+				return exportJSONL(j, r, f)
+			}
+		})
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Exported %d entries to %s\n", count, out)
+	return nil
+}
+
+// exportFile creates the file named by path and calls export to write it.
+// The file is removed if export fails.
+func exportFile(path string, export func(io.Writer) (int, error)) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("creating %s: %w", path, err)
+	}
+	count, err := export(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return count, nil
+}
+
+// streamEntries calls fn with each entry in the journal whose date is within
+// r, oldest first. The paths of all such entries are gathered and sorted by
+// date before the first is loaded; the entries are then loaded one at a
+// time, so fn must keep any it needs.
+func streamEntries(j *journal, r dateRange, fn func(*Entry) error) error {
+	done := make(chan struct{})
+	defer close(done)
+	paths, errc := walkFiles(done, j.root, j.layout, r)
+
+	type datedPath struct {
+		path string
+		date time.Time
+	}
+	var dated []datedPath
+	for path := range paths {
+		p := j.entry(path)
+		date, err := p.Date()
+		if err != nil {
+			return err
+		}
+		dated = append(dated, datedPath{path: path, date: date})
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	sort.Slice(dated, func(i, j int) bool {
+		return dated[i].date.Before(dated[j].date)
+	})
+
+	for _, d := range dated {
+		p := j.entry(d.path)
+		if _, err := p.Load(); err != nil {
+			return fmt.Errorf("loading %s: %w", d.path, err)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
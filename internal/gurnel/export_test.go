@@ -0,0 +1,234 @@
+package gurnel
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+// writeTestEntries creates an entry for each of bodies, one per day, ending
+// on the test clock's date.
+func writeTestEntries(t *testing.T, dir string, bodies ...string) []*Entry {
+	now := (&test.FixedClock{}).Now()
+	var entries []*Entry
+	for i, body := range bodies {
+		p, err := NewEntry(dir, DefaultLayout, now.AddDate(0, 0, i-len(bodies)+1))
+		if err != nil {
+			t.Fatalf("creating entry: %s", err)
+		}
+		p.Body = []byte(body)
+		p.AverageMood = uint8(i%5 + 1)
+		if err := p.Save(); err != nil {
+			t.Fatalf("saving entry: %s", err)
+		}
+		entries = append(entries, p)
+	}
+	return entries
+}
+
+func TestExportHTML(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		bodies    []string
+		tags      []string
+		overrides map[string]string
+		pages     map[string][]string
+	}{
+		{
+			desc:   "with no entries",
+			bodies: []string{},
+			pages: map[string][]string{
+				"index.html": {"0 entries"},
+				"tags.html":  {"no tags yet"},
+			},
+		},
+		{
+			desc:   "with several entries",
+			bodies: []string{"# First\n\n*emphasis*", "second entry", "third entry"},
+			tags:   []string{"travel"},
+			pages: map[string][]string{
+				"index.html": {
+					"3 entries", "<h2>2008</h2>", "<h3>April</h3>",
+					`href="entries/2008-04-10.html"`, "<svg", "<polyline", "<rect",
+				},
+				"tags.html": {`id="travel"`, "entries/2008-04-12.html"},
+				filepath.Join("entries", "2008-04-10.html"): {
+					"<h1>First</h1>", "<em>emphasis</em>", "2008-04-11.html",
+				},
+				filepath.Join("entries", "2008-04-11.html"): {
+					"2008-04-10.html", "2008-04-12.html", "mood 2",
+				},
+			},
+		},
+		{
+			desc:   "with tags written in the body",
+			bodies: []string{"a day of #Hiking", "more #hiking"},
+			tags:   []string{"travel"},
+			pages: map[string][]string{
+				"tags.html": {`id="hiking"`, "entries/2008-04-11.html", `id="travel"`},
+				filepath.Join("entries", "2008-04-12.html"): {"tags.html#hiking", "tags.html#travel"},
+			},
+		},
+		{
+			desc:      "with an overridden template",
+			bodies:    []string{"only entry"},
+			overrides: map[string]string{"entry.html": `{{define "entry"}}custom {{.Entry.Words}}{{end}}`},
+			pages: map[string][]string{
+				filepath.Join("entries", "2008-04-12.html"): {"custom 2"},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			outDir, err := ioutil.TempDir("", "gurnel_export")
+			if err != nil {
+				t.Fatalf("creating output dir: %s", err)
+			}
+			defer os.RemoveAll(outDir)
+
+			entries := writeTestEntries(t, dir, tC.bodies...)
+			if len(entries) > 0 {
+				last := entries[len(entries)-1]
+				last.Tags = tC.tags
+				if err := last.Save(); err != nil {
+					t.Fatalf("saving entry: %s", err)
+				}
+			}
+			tmplDir := filepath.Join(dir, journalConfigDir, templateDir, htmlTemplateDir)
+			if err := os.MkdirAll(tmplDir, 0700); err != nil {
+				t.Fatalf("creating template dir: %s", err)
+			}
+			for name, text := range tC.overrides {
+				if err := ioutil.WriteFile(filepath.Join(tmplDir, name), []byte(text), 0600); err != nil {
+					t.Fatalf("writing template: %s", err)
+				}
+			}
+
+			cmd := exportCmd{}
+			out := bytes.Buffer{}
+			conf := Config{clock: &test.FixedClock{}}
+			if err := cmd.Run(&bytes.Buffer{}, &out, []string{"html", outDir}, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+
+			for page, expected := range tC.pages {
+				data, err := ioutil.ReadFile(filepath.Join(outDir, page))
+				if err != nil {
+					t.Fatalf("reading page: %s", err)
+				}
+				test.CheckOutput(t, expected, string(data))
+			}
+		})
+	}
+}
+
+func TestExportFiles(t *testing.T) {
+	bodies := make([]string, 14)
+	for i := range bodies {
+		bodies[i] = "entry number " + strconv.Itoa(i)
+	}
+
+	testCases := []struct {
+		desc   string
+		args   []string
+		flags  exportCmd
+		checks func(t *testing.T, data []byte)
+	}{
+		{
+			desc:  "to JSON Lines within a date range",
+			args:  []string{"jsonl"},
+			flags: exportCmd{from: "2008-04-10", to: "2008-04-11"},
+			checks: func(t *testing.T, data []byte) {
+				lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+				if len(lines) != 2 {
+					t.Fatalf("expected 2 lines. got %d: %q", len(lines), data)
+				}
+				var e jsonEntry
+				if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+					t.Fatalf("decoding entry: %s", err)
+				}
+				if e.Date != "2008-04-10" || e.Words != 3 || e.Body != "entry number 11" {
+					t.Fatalf("wrong entry. got %+v", e)
+				}
+				if mood := e.Frontmatter["averagemood"]; mood != float64(2) {
+					t.Fatalf("wrong frontmatter. got %+v", e.Frontmatter)
+				}
+			},
+		},
+		{
+			desc: "to print-ready HTML",
+			args: []string{"print"},
+			checks: func(t *testing.T, data []byte) {
+				test.CheckOutput(t, []string{
+					"@page", `href="#2008-03"`, "<h1 id=\"2008-04\">April 2008</h1>",
+					"Sunday, March 30", "<p>entry number 13</p>",
+				}, string(data))
+			},
+		},
+		{
+			desc: "to EPUB",
+			args: []string{"epub"},
+			checks: func(t *testing.T, data []byte) {
+				z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Fatalf("reading zip: %s", err)
+				}
+				if f := z.File[0]; f.Name != "mimetype" || f.Method != zip.Store {
+					t.Fatalf("expected an uncompressed mimetype first. got %s", f.Name)
+				}
+				files := make(map[string]string)
+				for _, f := range z.File {
+					rc, _ := f.Open()
+					contents, _ := ioutil.ReadAll(rc)
+					rc.Close()
+					files[f.Name] = string(contents)
+				}
+				test.CheckOutput(t, []string{"2008-03.xhtml", "2008-04.xhtml", `properties="nav"`,
+					`<meta property="dcterms:modified">2008-04-12T16:00:00Z</meta>`},
+					files["OEBPS/content.opf"])
+				test.CheckOutput(t, []string{"March 2008", "April 2008"}, files["OEBPS/nav.xhtml"])
+				test.CheckOutput(t, []string{"<h1>March 2008</h1>", "entry number 0", "entry number 1"},
+					files["OEBPS/2008-03.xhtml"])
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			writeTestEntries(t, dir, bodies...)
+
+			out := bytes.Buffer{}
+			args := tC.args
+			outPath := filepath.Join(dir, "export.out")
+			if tC.args[0] != "jsonl" {
+				args = append(args, outPath)
+			}
+			// With a rollover hour, the journal's day lags the time of the export
+			conf := Config{DayRolloverHour: 4, clock: &test.FixedClock{}}
+			if err := tC.flags.Run(&bytes.Buffer{}, &out, args, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+
+			data := out.Bytes()
+			if tC.args[0] != "jsonl" {
+				test.CheckOutput(t, []string{"exported 14 entries"}, out.String())
+				var err error
+				if data, err = ioutil.ReadFile(outPath); err != nil {
+					t.Fatalf("reading export: %s", err)
+				}
+			}
+			tC.checks(t, data)
+		})
+	}
+}
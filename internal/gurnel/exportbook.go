@@ -0,0 +1,229 @@
+package gurnel
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/yuin/goldmark"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const epubStyle = `body { font-family: serif; line-height: 1.5; }
+h1 { page-break-before: always; }
+h2 { margin-top: 2em; }
+.meta { font-size: .8em; color: #666; }
+`
+
+const printTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Journal</title>
+<style>
+body { font: 11pt/1.5 Georgia, serif; color: #000; max-width: 40em; margin: 0 auto; }
+h1 { page-break-before: always; }
+h2 { page-break-after: avoid; margin-top: 2em; }
+.meta { font: 9pt sans-serif; color: #555; }
+nav ol { columns: 2; }
+@page { size: A5; margin: 20mm 15mm; }
+@media print { nav a { color: #000; text-decoration: none; } }
+</style>
+</head>
+<body>
+<nav><h1 style="page-break-before: avoid">Journal</h1>
+<ol>
+{{range .}}<li><a href="#{{.ID}}">{{.Title}}</a></li>
+{{end}}</ol></nav>
+{{range .}}<h1 id="{{.ID}}">{{.Title}}</h1>
+{{range .Entries}}<h2>{{.Date.Format "Monday, January 2"}}</h2>
+<p class="meta">{{.Words}} words{{if .AverageMood}} &middot; mood {{.AverageMood}}{{end}}</p>
+{{.Body}}
+{{end}}{{end}}</body>
+</html>
+`
+
+// bookChapter is a month of entries.
+type bookChapter struct {
+	ID      string
+	Title   string
+	Entries []*htmlEntry
+}
+
+// bookChapters groups the entries in the journal into a
+// chapter per month, with bodies rendered as XHTML. Entries are loaded one at
+// a time, and emit is called with each chapter as soon as it is complete.
+func bookChapters(
+	j *journal,
+	r dateRange,
+	emit func(*bookChapter) error,
+) (count int, err error) {
+	md := goldmark.New(goldmark.WithRendererOptions(gmhtml.WithXHTML()))
+	var chapter *bookChapter
+	err = streamEntries(j, r, func(p *Entry) error {
+		date, err := p.Date()
+		if err != nil {
+			return err
+		}
+		if id := date.Format("2006-01"); chapter == nil || chapter.ID != id {
+			if chapter != nil {
+				if err := emit(chapter); err != nil {
+					return err
+				}
+			}
+			chapter = &bookChapter{ID: id, Title: date.Format("January 2006")}
+		}
+		var body bytes.Buffer
+		if err := md.Convert(p.Body, &body); err != nil {
+			return fmt.Errorf("rendering %s: %w", p.Path, err)
+		}
+		chapter.Entries = append(chapter.Entries, &htmlEntry{
+			Date:        date,
+			Body:        template.HTML(body.String()), // #nosec
+			Words:       len(p.Words()),
+			AverageMood: p.AverageMood,
+		})
+		count++
+		return nil
+	})
+	if err == nil && chapter != nil {
+		err = emit(chapter)
+	}
+	return count, err
+}
+
+// exportPrint writes the entries in the journal to w as a
+// single HTML document styled for printing.
+func exportPrint(j *journal, r dateRange, w io.Writer) (int, error) {
+	var chapters []*bookChapter
+	count, err := bookChapters(j, r, func(c *bookChapter) error {
+		chapters = append(chapters, c)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	tmpl := template.Must(template.New("print").Parse(printTemplate))
+	return count, tmpl.Execute(w, chapters)
+}
+
+// exportEPUB writes the entries in the journal to w as an
+// EPUB 3 book with a chapter per month.
+func exportEPUB(j *journal, r dateRange, w io.Writer, now time.Time) (int, error) {
+	z := zip.NewWriter(w)
+
+	// The mimetype must come first, and be stored uncompressed.
+	mimetype, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return 0, err
+	}
+	for name, text := range map[string]string{
+		"META-INF/container.xml": epubContainer,
+		"OEBPS/style.css":        epubStyle,
+	} {
+		if err := writeZipFile(z, name, text); err != nil {
+			return 0, err
+		}
+	}
+
+	var chapters []*bookChapter
+	count, err := bookChapters(j, r, func(c *bookChapter) error {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, xhtmlHeader, html.EscapeString(c.Title))
+		fmt.Fprintf(&buf, "<h1>%s</h1>\n", html.EscapeString(c.Title))
+		for _, e := range c.Entries {
+			fmt.Fprintf(&buf, "<h2>%s</h2>\n", e.Date.Format("Monday, January 2"))
+			fmt.Fprintf(&buf, "<p class=\"meta\">%d words</p>\n%s\n", e.Words, e.Body)
+		}
+		buf.WriteString(xhtmlFooter)
+		// Bodies aren't needed once the chapter is written.
+		chapters = append(chapters, &bookChapter{ID: c.ID, Title: c.Title})
+		return writeZipFile(z, "OEBPS/"+c.ID+".xhtml", buf.String())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var nav, manifest, spine bytes.Buffer
+	for _, c := range chapters {
+		fmt.Fprintf(&nav, "<li><a href=\"%s.xhtml\">%s</a></li>\n", c.ID, html.EscapeString(c.Title))
+		fmt.Fprintf(&manifest,
+			"<item id=\"c%s\" href=\"%s.xhtml\" media-type=\"application/xhtml+xml\"/>\n", c.ID, c.ID)
+		fmt.Fprintf(&spine, "<itemref idref=\"c%s\"/>\n", c.ID)
+	}
+	if err := writeZipFile(z, "OEBPS/nav.xhtml", fmt.Sprintf(xhtmlHeader, "Contents")+
+		"<nav epub:type=\"toc\"><h1>Contents</h1>\n<ol>\n"+nav.String()+"</ol></nav>\n"+xhtmlFooter); err != nil {
+		return 0, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return 0, err
+	}
+	opf := fmt.Sprintf(epubPackage, id, now.UTC().Format(time.RFC3339), manifest.String(), spine.String())
+	if err := writeZipFile(z, "OEBPS/content.opf", opf); err != nil {
+		return 0, err
+	}
+	return count, z.Close()
+}
+
+const xhtmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>%s</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>
+`
+
+const xhtmlFooter = "</body>\n</html>\n"
+
+const epubPackage = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="id">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="id">urn:uuid:%s</dc:identifier>
+<dc:title>Journal</dc:title>
+<dc:language>en</dc:language>
+<meta property="dcterms:modified">%s</meta>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="style" href="style.css" media-type="text/css"/>
+%s</manifest>
+<spine>
+%s</spine>
+</package>
+`
+
+func writeZipFile(z *zip.Writer, name, text string) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, text)
+	return err
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
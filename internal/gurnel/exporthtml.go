@@ -0,0 +1,283 @@
+package gurnel
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+)
+
+const htmlTemplateDir = "html"
+
+const baseTemplate = `{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 42em; margin: 2em auto; padding: 0 1em; font: 17px/1.6 Georgia, serif; color: #222; }
+nav, .meta, footer { font: 14px/1.4 sans-serif; color: #666; }
+nav a, footer a { margin-right: 1em; }
+.tags a { margin-right: .5em; }
+svg { width: 100%; height: auto; }
+svg polyline { fill: none; stroke: #36c; stroke-width: 2; }
+svg rect { fill: #36c; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Journal</a><a href="{{.Root}}tags.html">Tags</a></nav>
+{{end}}
+{{define "footer"}}</body>
+</html>
+{{end}}`
+
+const indexTemplate = `{{define "index"}}{{template "header" .}}
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Entries}} entries, {{.Words}} words</p>
+{{with .MoodChart}}<h2>Average mood</h2>
+{{.}}{{end}}
+{{with .WordChart}}<h2>Words</h2>
+{{.}}{{end}}
+{{range .Years}}<h2>{{.Year}}</h2>
+{{range .Months}}<h3>{{.Month.Format "January"}}</h3>
+<ul>
+{{range .Entries}}<li><a href="{{.Page}}">{{.Date.Format "Monday, January 2"}}</a> <span class="meta">{{.Words}} words</span></li>
+{{end}}</ul>
+{{end}}{{end}}{{template "footer" .}}{{end}}`
+
+const entryTemplate = `{{define "entry"}}{{template "header" .}}
+{{with .Entry}}<h1>{{.Date.Format "Monday, January 2, 2006"}}</h1>
+<p class="meta">{{.Words}} words{{if .AverageMood}} &middot; mood {{.AverageMood}} (low {{.LowMood}}, high {{.HighMood}}){{end}}</p>
+{{if .Tags}}<p class="meta tags">{{range .Tags}}<a href="{{$.Root}}tags.html#{{.}}">#{{.}}</a>{{end}}</p>{{end}}
+{{.Body}}
+<footer>
+{{with .Prev}}<a href="{{$.Root}}{{.Page}}">&larr; {{.Date.Format "Jan 2, 2006"}}</a>{{end}}
+{{with .Next}}<a href="{{$.Root}}{{.Page}}">{{.Date.Format "Jan 2, 2006"}} &rarr;</a>{{end}}
+</footer>
+{{end}}{{template "footer" .}}{{end}}`
+
+const tagsTemplate = `{{define "tags"}}{{template "header" .}}
+<h1>Tags</h1>
+{{range .Tags}}<h2 id="{{.Name}}">#{{.Name}}</h2>
+<ul>
+{{range .Entries}}<li><a href="{{.Page}}">{{.Date.Format "January 2, 2006"}}</a></li>
+{{end}}</ul>
+{{else}}<p>No tags yet.</p>
+{{end}}{{template "footer" .}}{{end}}`
+
+type htmlEntry struct {
+	Date        time.Time
+	Page        string
+	Body        template.HTML
+	Words       int
+	LowMood     uint8
+	HighMood    uint8
+	AverageMood uint8
+	Tags        []string
+	Prev, Next  *htmlEntry
+}
+
+type htmlMonth struct {
+	Month   time.Time
+	Entries []*htmlEntry
+}
+
+type htmlYear struct {
+	Year   int
+	Months []*htmlMonth
+}
+
+type htmlTag struct {
+	Name    string
+	Entries []*htmlEntry
+}
+
+type htmlPage struct {
+	Title     string
+	Root      string
+	Entries   int
+	Words     int
+	Years     []*htmlYear
+	Tags      []*htmlTag
+	Entry     *htmlEntry
+	MoodChart template.HTML
+	WordChart template.HTML
+}
+
+// exportHTML renders entries, oldest first, as a static site in outDir.
+// Templates in the journal rooted at root override the defaults.
+func exportHTML(root string, entries []*Entry, outDir string) error {
+	tmpl, err := htmlTemplates(root)
+	if err != nil {
+		return err
+	}
+
+	page := htmlPage{Title: "Journal"}
+	var all []*htmlEntry
+	tags := make(map[string]*htmlTag)
+	for _, p := range entries {
+		date, err := p.Date()
+		if err != nil {
+			return err
+		}
+		var body bytes.Buffer
+		if err := goldmark.Convert(p.Body, &body); err != nil {
+			return fmt.Errorf("rendering %s: %w", p.Path, err)
+		}
+		names := p.allTags()
+		e := &htmlEntry{
+			Date:        date,
+			Page:        "entries/" + date.Format(dateArgFormat) + ".html",
+			Body:        template.HTML(body.String()), // #nosec
+			Words:       len(p.Words()),
+			LowMood:     p.LowMood,
+			HighMood:    p.HighMood,
+			AverageMood: p.AverageMood,
+			Tags:        names,
+		}
+		if n := len(all); n > 0 {
+			e.Prev, all[n-1].Next = all[n-1], e
+		}
+		all = append(all, e)
+		page.Words += e.Words
+
+		if n := len(page.Years); n == 0 || page.Years[n-1].Year != date.Year() {
+			page.Years = append(page.Years, &htmlYear{Year: date.Year()})
+		}
+		year := page.Years[len(page.Years)-1]
+		if n := len(year.Months); n == 0 || year.Months[n-1].Month.Month() != date.Month() {
+			year.Months = append(year.Months, &htmlMonth{Month: date})
+		}
+		month := year.Months[len(year.Months)-1]
+		month.Entries = append(month.Entries, e)
+
+		for _, name := range names {
+			if tags[name] == nil {
+				tags[name] = &htmlTag{Name: name}
+				page.Tags = append(page.Tags, tags[name])
+			}
+			tags[name].Entries = append(tags[name].Entries, e)
+		}
+	}
+	page.Entries = len(all)
+	sort.Slice(page.Tags, func(i, j int) bool { return page.Tags[i].Name < page.Tags[j].Name })
+	page.MoodChart = lineChart(all, func(e *htmlEntry) float64 { return float64(e.AverageMood) }, 5)
+	page.WordChart = barChart(all, func(e *htmlEntry) float64 { return float64(e.Words) })
+
+	if err := os.MkdirAll(filepath.Join(outDir, "entries"), 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	if err := renderPage(tmpl, "index", filepath.Join(outDir, "index.html"), page); err != nil {
+		return err
+	}
+	if err := renderPage(tmpl, "tags", filepath.Join(outDir, "tags.html"), page); err != nil {
+		return err
+	}
+	for _, e := range all {
+		entryPage := htmlPage{
+			Title: e.Date.Format("January 2, 2006"),
+			Root:  "../",
+			Entry: e,
+		}
+		if err := renderPage(tmpl, "entry", filepath.Join(outDir, filepath.FromSlash(e.Page)), entryPage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// htmlTemplates parses the default templates, then any overrides in the
+// journal rooted at root.
+func htmlTemplates(root string) (*template.Template, error) {
+	tmpl := template.New("site")
+	for _, text := range []string{baseTemplate, indexTemplate, entryTemplate, tagsTemplate} {
+		template.Must(tmpl.Parse(text))
+	}
+
+	dir := filepath.Join(root, journalConfigDir, templateDir, htmlTemplateDir)
+	overrides, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range overrides {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading template: %w", err)
+		}
+		if _, err := tmpl.Parse(string(text)); err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", path, err)
+		}
+	}
+	return tmpl, nil
+}
+
+func renderPage(tmpl *template.Template, name, path string, data htmlPage) error {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("rendering %s: %w", path, err)
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+const (
+	chartWidth  = 600
+	chartHeight = 120
+)
+
+// lineChart returns an SVG line chart of value over entries, skipping
+// entries where it is zero. Values range from zero to max.
+func lineChart(entries []*htmlEntry, value func(*htmlEntry) float64, max float64) template.HTML {
+	var points []string
+	for i, e := range entries {
+		v := value(e)
+		if v == 0 {
+			continue
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f",
+			chartX(i, len(entries)), chartHeight-v/max*chartHeight))
+	}
+	if len(points) < 2 {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf( // #nosec
+		`<svg viewBox="0 0 %d %d" role="img"><polyline points="%s"/></svg>`,
+		chartWidth, chartHeight, strings.Join(points, " ")))
+}
+
+// barChart returns an SVG bar chart of value over entries.
+func barChart(entries []*htmlEntry, value func(*htmlEntry) float64) template.HTML {
+	var max float64
+	for _, e := range entries {
+		if v := value(e); v > max {
+			max = v
+		}
+	}
+	if max == 0 {
+		return ""
+	}
+	var bars strings.Builder
+	width := float64(chartWidth) / float64(len(entries))
+	for i, e := range entries {
+		height := value(e) / max * chartHeight
+		fmt.Fprintf(&bars, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`,
+			float64(i)*width, chartHeight-height, width*0.8, height)
+	}
+	return template.HTML(fmt.Sprintf( // #nosec
+		`<svg viewBox="0 0 %d %d" role="img">%s</svg>`,
+		chartWidth, chartHeight, bars.String()))
+}
+
+func chartX(i, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	return float64(i) / float64(n-1) * chartWidth
+}
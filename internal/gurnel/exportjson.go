@@ -0,0 +1,80 @@
+package gurnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/mikeraimondi/frontmatter/v2"
+)
+
+// jsonEntry is the JSON Lines representation of an entry.
+type jsonEntry struct {
+	Date        string                 `json:"date"`
+	Words       int                    `json:"words"`
+	Body        string                 `json:"body"`
+	Frontmatter map[string]interface{} `json:"frontmatter"`
+}
+
+// exportJSONL writes the entries in the journal to w as JSON Lines, one
+// entry at a time.
+func exportJSONL(j *journal, r dateRange, w io.Writer) (count int, err error) {
+	enc := json.NewEncoder(w)
+	err = streamEntries(j, r, func(p *Entry) error {
+		date, err := p.Date()
+		if err != nil {
+			return err
+		}
+		fm, err := rawFrontmatter(p.Path)
+		if err != nil {
+			return err
+		}
+		count++
+		return enc.Encode(jsonEntry{
+			Date:        date.Format(dateArgFormat),
+			Words:       len(p.Words()),
+			Body:        string(p.Body),
+			Frontmatter: fm,
+		})
+	})
+	return count, err
+}
+
+// rawFrontmatter returns all the frontmatter of the file named by path,
+// including fields unknown to Entry, in a form that can be encoded as JSON.
+func rawFrontmatter(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fm map[interface{}]interface{}
+	if _, err := frontmatter.Unmarshal(data, &fm); err != nil {
+		return nil, fmt.Errorf("parsing frontmatter of %s: %w", path, err)
+	}
+	m, _ := jsonValue(fm).(map[string]interface{})
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	return m, nil
+}
+
+// jsonValue converts v, as decoded from YAML, into a value that
+// encoding/json can encode, by turning maps' keys into strings.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,129 @@
+package gurnel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const backupDir = "backups"
+
+// SaveError is returned when an Entry could not be saved. If the Entry's
+// contents could be preserved elsewhere, RecoveryPath names that file.
+type SaveError struct {
+	Err          error
+	RecoveryPath string
+}
+
+func (e *SaveError) Error() string {
+	if e.RecoveryPath == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s (contents saved to %s)", e.Err, e.RecoveryPath)
+}
+
+func (e *SaveError) Unwrap() error { return e.Err }
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// path, syncs it to disk, and renames it over path, so that path holds either
+// its previous contents or data, and never a partial write. The mode of an
+// existing file is preserved.
+func writeFileAtomic(path string, data []byte) (err error) {
+	var perm os.FileMode = 0644
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir, name := filepath.Split(path)
+	f, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err = f.Chmod(perm); err != nil {
+		return fmt.Errorf("setting permissions: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	// Persist the rename itself. Not all platforms support syncing a
+	// directory, so failures here are ignored.
+	if d, dirErr := os.Open(filepath.Clean(dir)); dirErr == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// backupFile copies the file named by path into the backups directory under
+// root, keeping at most n previous versions. backup.1 is the most recent.
+func backupFile(root, path string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+
+	base := filepath.Join(root, journalConfigDir, backupDir, journalRel(root, path)) + "."
+	if err := os.MkdirAll(filepath.Dir(base), 0700); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+	if err := os.Remove(base + strconv.Itoa(n)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing oldest backup: %w", err)
+	}
+	for i := n - 1; i > 0; i-- {
+		err := os.Rename(base+strconv.Itoa(i), base+strconv.Itoa(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotating backups: %w", err)
+		}
+	}
+	return writeFileAtomic(base+"1", data)
+}
+
+// journalRel returns path relative to the journal root, or its base name if
+// it is outside the journal.
+func journalRel(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(path)
+	}
+	return rel
+}
+
+// writeRecoveryFile writes data to a new file in the system temp directory
+// and returns its name.
+func writeRecoveryFile(name string, data []byte) (string, error) {
+	f, err := ioutil.TempFile("", "gurnel-recovery-*-"+name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
@@ -0,0 +1,137 @@
+package gurnel
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestEntrySaveBackups(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		backups int
+		saves   int
+		want    []string
+	}{
+		{
+			desc:    "with backups disabled",
+			backups: 0,
+			saves:   3,
+			want:    []string{},
+		},
+		{
+			desc:    "with fewer saves than backups",
+			backups: 3,
+			saves:   2,
+			want:    []string{"save 1", ""},
+		},
+		{
+			desc:    "with more saves than backups",
+			backups: 2,
+			saves:   5,
+			want:    []string{"save 4", "save 3"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+
+			p, err := NewEntry(dir, DefaultLayout, (&test.FixedClock{}).Now())
+			if err != nil {
+				t.Fatalf("creating entry: %s", err)
+			}
+			p.Backups = tC.backups
+			for i := 1; i <= tC.saves; i++ {
+				p.Body = []byte("save " + strconv.Itoa(i))
+				if err := p.Save(); err != nil {
+					t.Fatalf("expected no error. got %s", err)
+				}
+			}
+
+			backups, _ := ioutil.ReadDir(filepath.Join(dir, journalConfigDir, backupDir))
+			if len(backups) != len(tC.want) {
+				t.Fatalf("expected %d backups. got %d", len(tC.want), len(backups))
+			}
+			for i, want := range tC.want {
+				path := filepath.Join(dir, journalConfigDir, backupDir,
+					filepath.Base(p.Path)+"."+strconv.Itoa(i+1))
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatalf("reading backup: %s", err)
+				}
+				if !strings.HasSuffix(string(data), "---\n"+want) {
+					t.Fatalf("wrong backup contents. expected body %q. got %q", want, data)
+				}
+			}
+
+			files, _ := ioutil.ReadDir(dir)
+			for _, f := range files {
+				if strings.Contains(f.Name(), ".tmp") {
+					t.Fatalf("expected no temp files to remain. got %s", f.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutMatchWithBackups(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+
+	p, err := NewEntry(dir, DefaultLayout, (&test.FixedClock{}).Now())
+	if err != nil {
+		t.Fatalf("creating entry: %s", err)
+	}
+	p.Backups = 1
+	if err := p.Save(); err != nil {
+		t.Fatalf("saving entry: %s", err)
+	}
+	name := filepath.Base(p.Path)
+	backup := filepath.Join(journalConfigDir, backupDir, name+".1")
+	if _, err := os.Stat(filepath.Join(dir, backup)); err != nil {
+		t.Fatalf("expected a backup. got %s", err)
+	}
+	temp := "." + name + ".tmp123"
+
+	if !DefaultLayout.Match(name) {
+		t.Fatalf("expected %s to be an entry", name)
+	}
+	for _, rel := range []string{backup, temp} {
+		if DefaultLayout.Match(rel) {
+			t.Fatalf("expected %s not to be an entry", rel)
+		}
+	}
+}
+
+func TestEntrySaveRecovery(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+
+	p := &Entry{
+		Path: filepath.Join(dir, "missing", "entry.md"),
+		Body: []byte("irreplaceable words"),
+	}
+	err := p.Save()
+	var saveErr *SaveError
+	if !errors.As(err, &saveErr) {
+		t.Fatalf("expected a *SaveError. got %v", err)
+	}
+	if saveErr.RecoveryPath == "" {
+		t.Fatal("expected a recovery path")
+	}
+	defer os.Remove(saveErr.RecoveryPath)
+
+	data, err := ioutil.ReadFile(saveErr.RecoveryPath)
+	if err != nil {
+		t.Fatalf("reading recovery file: %s", err)
+	}
+	test.CheckOutput(t, []string{"irreplaceable words"}, string(data))
+	test.CheckOutput(t, []string{saveErr.RecoveryPath}, saveErr.Error())
+}
@@ -0,0 +1,33 @@
+package gurnel
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// git runs git with args in the directory named by dir. If git fails, its
+// output is included in the returned error.
+func git(dir string, args ...string) error {
+	_, err := gitOutput(dir, args...)
+	return err
+}
+
+// gitOutput runs git like git does, and returns its standard output.
+func gitOutput(dir string, args ...string) (string, error) {
+	var out, stderr bytes.Buffer
+	// #nosec
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String() + "\n" + out.String())
+		if msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,83 @@
+package gurnel
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HooksConfig lists commands to run at each stage of writing an entry. Each
+// is run by the shell in the journal's directory, with details of the entry
+// in environment variables:
+//
+//	GURNEL_HOOK          the stage, such as "pre-commit"
+//	GURNEL_JOURNAL       the root of the journal
+//	GURNEL_ENTRY         the entry's file
+//	GURNEL_DATE          the entry's date, as YYYY-MM-DD
+//	GURNEL_WORDS         the entry's word count
+//	GURNEL_SECONDS       the time spent writing the entry
+//	GURNEL_LOW_MOOD, GURNEL_HIGH_MOOD, GURNEL_AVERAGE_MOOD
+//	GURNEL_TAGS          the entry's tags, separated by commas
+//
+// If a pre-edit or pre-commit hook fails, the editor isn't opened or the
+// entry isn't committed. Failures of the other hooks are reported, but
+// don't stop anything. For an encrypted entry, the edit hooks are given the
+// decrypted copy being edited.
+type HooksConfig struct {
+	PreEdit    []string
+	PostEdit   []string
+	PreCommit  []string
+	PostCommit []string
+}
+
+// hookEnv returns the environment hooks for p are run with. edit is the file
+// being edited, which is a decrypted copy of p for an encrypted entry, and is
+// where the entry's contents are read from.
+func hookEnv(stage, root string, p, edit *Entry) []string {
+	env := map[string]string{
+		"GURNEL_HOOK":         stage,
+		"GURNEL_JOURNAL":      root,
+		"GURNEL_ENTRY":        edit.Path,
+		"GURNEL_WORDS":        strconv.Itoa(len(edit.Words())),
+		"GURNEL_SECONDS":      strconv.Itoa(int(time.Duration(edit.TimeSpent).Seconds())),
+		"GURNEL_LOW_MOOD":     strconv.Itoa(int(edit.LowMood)),
+		"GURNEL_HIGH_MOOD":    strconv.Itoa(int(edit.HighMood)),
+		"GURNEL_AVERAGE_MOOD": strconv.Itoa(int(edit.AverageMood)),
+		"GURNEL_TAGS":         strings.Join(edit.Tags, ","),
+	}
+	// The date comes from where the entry is kept in the journal
+	if date, err := p.Date(); err == nil {
+		env["GURNEL_DATE"] = date.Format(dateArgFormat)
+	}
+	vars := os.Environ()
+	for name, value := range env {
+		vars = append(vars, name+"="+value)
+	}
+	return vars
+}
+
+// runHooks runs each of commands for the stage, in order, with output going
+// to w. edit is given to the hooks as the entry's file, as in hookEnv.
+// runHooks stops at the first hook to fail.
+func runHooks(w io.Writer, stage string, commands []string, root string, p, edit *Entry) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	env := hookEnv(stage, root, p, edit)
+	for _, command := range commands {
+		// #nosec
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = root
+		cmd.Env = env
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q: %w", stage, command, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package gurnel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikeraimondi/frontmatter/v2"
+)
+
+type importCmd struct {
+	merge      bool
+	dryRun     bool
+	dateFormat string
+}
+
+func (*importCmd) Name() string      { return "import" }
+func (*importCmd) ShortHelp() string { return "Import entries from other journaling tools" }
+
+func (c *importCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.BoolVar(&c.merge, "merge", false, "append to entries that already exist")
+	fs.BoolVar(&c.dryRun, "n", false, "report what would be imported without writing anything")
+	fs.StringVar(&c.dateFormat, "date-format", "2006-01-02",
+		"name of Obsidian daily notes, in Go's reference time")
+	return fs
+}
+
+func (*importCmd) LongHelp() string {
+	return `Converts entries from another tool into this journal. Formats are:
+
+  dayone <file>    a Day One JSON export
+  jrnl <file>      a jrnl plain text journal
+  obsidian <dir>   a folder of Obsidian daily notes
+
+Entries for days that already exist in the journal are skipped, unless -merge
+is given, in which case they're appended. Use -n to see what would happen
+without changing anything.
+
+Tags aren't imported into an encrypted journal, since its frontmatter is
+stored in plain text.`
+}
+
+// importedEntry is a day's writing read from another tool.
+type importedEntry struct {
+	date time.Time
+	body []byte
+	tags []string
+}
+
+func (c *importCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	if len(args) != 2 {
+		return errors.New("a format and a path must be given")
+	}
+	format, path := args[0], args[1]
+
+	var imported []*importedEntry
+	var err error
+	switch format {
+	case "dayone":
+		imported, err = readDayOne(path)
+	case "jrnl":
+		imported, err = readJrnl(path)
+	case "obsidian":
+		imported, err = readObsidian(path, c.dateFormat)
+	default:
+		return fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	imported = combineDays(imported)
+
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+
+	var created, merged, skipped int
+	for _, ie := range imported {
+		day := ie.date.Format(dateArgFormat)
+		p := j.entryFor(ie.date)
+		_, err := p.Load()
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("loading %s: %w", p.Path, err)
+		}
+
+		switch {
+		case exists && !c.merge:
+			fmt.Fprintf(w, "%s: skipped, entry exists\n", day)
+			skipped++
+			continue
+		case exists:
+			fmt.Fprintf(w, "%s: merged\n", day)
+			merged++
+			p.Body = append(bytes.TrimRight(p.Body, "\n"), "\n\n"...)
+		default:
+			fmt.Fprintf(w, "%s: created\n", day)
+			created++
+		}
+		if c.dryRun {
+			continue
+		}
+
+		p.Body = append(p.Body, ie.body...)
+		// Frontmatter is stored in plain text, so an encrypted journal
+		// doesn't get the imported tags
+		if j.key == nil {
+			p.Tags = mergeTags(p.Tags, ie.tags)
+		}
+		if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		if err := p.Save(); err != nil {
+			return fmt.Errorf("saving %s: %w", p.Path, err)
+		}
+	}
+
+	verb := "Imported"
+	if c.dryRun {
+		verb = "Would import"
+	}
+	fmt.Fprintf(w, "%s %d entries: %d created, %d merged, %d skipped\n",
+		verb, created+merged, created, merged, skipped)
+	if skipped > 0 && !c.merge {
+		fmt.Fprintln(w, "Use -merge to append to existing entries")
+	}
+	return nil
+}
+
+// combineDays merges entries for the same day, in order, and sorts the
+// result by date.
+func combineDays(entries []*importedEntry) []*importedEntry {
+	byDay := make(map[string]*importedEntry)
+	var days []*importedEntry
+	for _, ie := range entries {
+		key := ie.date.Format(dateArgFormat)
+		if existing, ok := byDay[key]; ok {
+			existing.body = append(append(bytes.TrimRight(existing.body, "\n"), "\n\n"...), ie.body...)
+			existing.tags = mergeTags(existing.tags, ie.tags)
+			continue
+		}
+		byDay[key] = ie
+		days = append(days, ie)
+	}
+	sort.SliceStable(days, func(i, j int) bool {
+		return days[i].date.Before(days[j].date)
+	})
+	return days
+}
+
+// mergeTags returns the union of a and b, in order.
+func mergeTags(a, b []string) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, tag := range append(append([]string{}, a...), b...) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// readDayOne reads a Day One JSON export. Entries are dated in the time zone
+// they were written in.
+func readDayOne(path string) ([]*importedEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var export struct {
+		Entries []struct {
+			CreationDate time.Time
+			TimeZone     string
+			Text         string
+			Tags         []string
+		}
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	var entries []*importedEntry
+	for _, e := range export.Entries {
+		created := e.CreationDate
+		if loc, err := time.LoadLocation(e.TimeZone); e.TimeZone != "" && err == nil {
+			created = created.In(loc)
+		}
+		entries = append(entries, &importedEntry{
+			date: dayDate(created),
+			body: []byte(strings.TrimSpace(e.Text) + "\n"),
+			tags: e.Tags,
+		})
+	}
+	return entries, nil
+}
+
+var jrnlHeading = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}) \d{2}:\d{2}(?::\d{2})?(?: ?[AaPp][Mm])?\]? ?(.*)$`)
+
+// readJrnl reads a jrnl plain text journal, in which each entry begins with
+// a line like "[2006-01-02 15:04] Title".
+func readJrnl(path string) ([]*importedEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*importedEntry
+	var current *importedEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := jrnlHeading.FindStringSubmatch(line); m != nil {
+			date, err := time.Parse(dateArgFormat, m[1])
+			if err != nil {
+				return nil, err
+			}
+			current = &importedEntry{date: date}
+			entries = append(entries, current)
+			line = m[2]
+		}
+		if current == nil {
+			continue
+		}
+		current.body = append(current.body, line+"\n"...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		e.body = append(bytes.TrimSpace(e.body), '\n')
+	}
+	return entries, nil
+}
+
+// readObsidian reads a directory of Obsidian daily notes, named according to
+// dateFormat. Tags in the notes' frontmatter are kept.
+func readObsidian(dir, dateFormat string) ([]*importedEntry, error) {
+	var entries []*importedEntry
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		name := strings.TrimSuffix(info.Name(), ".md")
+		if !info.Mode().IsRegular() || name == info.Name() {
+			return nil
+		}
+		date, err := time.Parse(dateFormat, name)
+		if err != nil {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var fm struct {
+			Tags interface{}
+		}
+		body, err := frontmatter.Unmarshal(data, &fm)
+		if err != nil {
+			return fmt.Errorf("parsing frontmatter of %s: %w", path, err)
+		}
+		entries = append(entries, &importedEntry{
+			date: dayDate(date),
+			body: append(bytes.TrimSpace(body), '\n'),
+			tags: obsidianTags(fm.Tags),
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// obsidianTags normalizes Obsidian's tags frontmatter, which may be a list
+// or a comma- or space-separated string.
+func obsidianTags(v interface{}) []string {
+	var raw []string
+	switch v := v.(type) {
+	case string:
+		raw = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		for _, tag := range v {
+			raw = append(raw, fmt.Sprint(tag))
+		}
+	}
+	var tags []string
+	for _, tag := range raw {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
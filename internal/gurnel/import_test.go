@@ -0,0 +1,192 @@
+package gurnel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestImport(t *testing.T) {
+	const dayOne = `{"metadata": {"version": "1.0"}, "entries": [
+		{"creationDate": "2008-04-11T02:30:00Z", "timeZone": "America/New_York",
+		 "text": "late night in new york", "tags": ["travel"]},
+		{"creationDate": "2008-04-12T15:00:00Z", "text": "afternoon"},
+		{"creationDate": "2008-04-12T20:00:00Z", "text": "evening", "tags": ["home"]}
+	]}`
+	const jrnl = "[2008-04-11 09:00] Morning pages\nfirst body line\n\n" +
+		"[2008-04-12 21:15] Evening @walk\nsecond body\n"
+
+	testCases := []struct {
+		desc     string
+		format   string
+		files    map[string]string
+		cmd      importCmd
+		existing string
+		out      []string
+		entries  map[string][]string
+		absent   []string
+	}{
+		{
+			desc:   "from Day One",
+			format: "dayone",
+			files:  map[string]string{"export.json": dayOne},
+			out:    []string{"2008-04-10: created", "2008-04-12: created", "2 created"},
+			entries: map[string][]string{
+				"2008-04-10": {"late night in new york", "- travel"},
+				"2008-04-12": {"afternoon\n\nevening", "- home"},
+			},
+		},
+		{
+			desc:   "from jrnl",
+			format: "jrnl",
+			files:  map[string]string{"journal.txt": jrnl},
+			out:    []string{"2 created"},
+			entries: map[string][]string{
+				"2008-04-11": {"Morning pages\nfirst body line"},
+				"2008-04-12": {"Evening @walk\nsecond body"},
+			},
+		},
+		{
+			desc:   "from Obsidian",
+			format: "obsidian",
+			files: map[string]string{
+				filepath.Join("notes", "Daily", "2008-04-12.md"): "---\ntags: [work, \"#focus\"]\n---\nshipped it\n",
+				filepath.Join("notes", "Ideas.md"):               "not a daily note",
+			},
+			out: []string{"1 created"},
+			entries: map[string][]string{
+				"2008-04-12": {"shipped it", "- work", "- focus"},
+			},
+			absent: []string{"not a daily note"},
+		},
+		{
+			desc:     "with an existing entry",
+			format:   "jrnl",
+			files:    map[string]string{"journal.txt": jrnl},
+			existing: "already written",
+			out:      []string{"2008-04-12: skipped", "1 created", "1 skipped", "use -merge"},
+			entries: map[string][]string{
+				"2008-04-12": {"already written"},
+			},
+			absent: []string{"second body"},
+		},
+		{
+			desc:     "with an existing entry and merging",
+			format:   "jrnl",
+			files:    map[string]string{"journal.txt": jrnl},
+			cmd:      importCmd{merge: true},
+			existing: "already written",
+			out:      []string{"2008-04-12: merged", "1 merged"},
+			entries: map[string][]string{
+				"2008-04-12": {"already written\n\nEvening @walk"},
+			},
+		},
+		{
+			desc:   "with a dry run",
+			format: "jrnl",
+			files:  map[string]string{"journal.txt": jrnl},
+			cmd:    importCmd{dryRun: true},
+			out:    []string{"2008-04-11: created", "would import 2 entries"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			src, err := ioutil.TempDir("", "gurnel_import")
+			if err != nil {
+				t.Fatalf("creating source dir: %s", err)
+			}
+			defer os.RemoveAll(src)
+			var srcPath string
+			for name, contents := range tC.files {
+				path := filepath.Join(src, name)
+				os.MkdirAll(filepath.Dir(path), 0700)
+				if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+					t.Fatalf("writing source: %s", err)
+				}
+				srcPath = path
+			}
+			if tC.format == "obsidian" {
+				srcPath = filepath.Join(src, "notes")
+			}
+			if tC.existing != "" {
+				writeTestEntries(t, dir, tC.existing)
+			}
+
+			if tC.cmd.dateFormat == "" {
+				tC.cmd.dateFormat = "2006-01-02"
+			}
+			out := bytes.Buffer{}
+			conf := Config{clock: &test.FixedClock{}}
+			if err := tC.cmd.Run(&bytes.Buffer{}, &out, []string{tC.format, srcPath}, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, tC.out, out.String())
+
+			files, _ := ioutil.ReadDir(dir)
+			var entryCount int
+			for _, f := range files {
+				if DefaultLayout.Match(f.Name()) {
+					entryCount++
+				}
+			}
+			expectedCount := len(tC.entries)
+			if tC.cmd.dryRun {
+				expectedCount = 0
+			} else if tC.existing != "" {
+				expectedCount = 2
+			}
+			if entryCount != expectedCount {
+				t.Fatalf("expected %d entries. got %d", expectedCount, entryCount)
+			}
+
+			for day, expected := range tC.entries {
+				date, _ := parseDateArg(day, conf.clock.Now())
+				data, err := ioutil.ReadFile(filepath.Join(dir, DefaultLayout.Path(date)))
+				if err != nil {
+					t.Fatalf("reading entry: %s", err)
+				}
+				test.CheckOutput(t, expected, string(data))
+				for _, absent := range tC.absent {
+					if bytes.Contains(data, []byte(absent)) {
+						t.Fatalf("expected entry not to contain %q. got %q", absent, data)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestImportEncrypted(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	os.Setenv(passphraseEnv, "correct horse")
+	defer os.Unsetenv(passphraseEnv)
+	src := filepath.Join(dir, "export.json")
+	dayOne := `{"entries": [{"creationDate": "2008-04-12T15:00:00Z", "text": "afternoon", "tags": ["travel"]}]}`
+	if err := ioutil.WriteFile(src, []byte(dayOne), 0600); err != nil {
+		t.Fatalf("writing source: %s", err)
+	}
+
+	conf := Config{clock: &test.FixedClock{}}
+	conf.Encryption.Enabled = true
+	cmd := importCmd{dateFormat: "2006-01-02"}
+	if err := cmd.Run(&bytes.Buffer{}, &bytes.Buffer{}, []string{"dayone", src}, &conf); err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, DefaultLayout.Path(conf.clock.Now())))
+	if err != nil {
+		t.Fatalf("reading entry: %s", err)
+	}
+	for _, absent := range []string{"afternoon", "travel"} {
+		if bytes.Contains(data, []byte(absent)) {
+			t.Fatalf("expected entry not to contain %q. got %q", absent, data)
+		}
+	}
+}
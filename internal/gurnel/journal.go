@@ -0,0 +1,58 @@
+package gurnel
+
+import (
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// journal is a directory of entries kept according to a layout. If key is
+// set, the bodies of entries are encrypted with it.
+type journal struct {
+	root   string
+	layout Layout
+	key    *[keySize]byte
+}
+
+// openJournal opens the journal in the working directory, deriving its key
+// if conf enables encryption. Any errors reported while getting the
+// passphrase are written to w.
+func openJournal(w io.Writer, conf *Config) (*journal, error) {
+	j, err := findJournal(conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.unlock(w, conf); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// findJournal returns the journal in the working directory without deriving
+// its key, for reading only what's kept in plain text.
+func findJournal(conf *Config) (*journal, error) {
+	wd, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	return &journal{root: wd, layout: layoutOrDefault(conf.Layout)}, nil
+}
+
+// unlock derives j's key, if conf enables encryption and it hasn't been
+// derived already.
+func (j *journal) unlock(w io.Writer, conf *Config) (err error) {
+	if conf.Encryption.Enabled && j.key == nil {
+		j.key, err = journalKey(w, j.root, &conf.Encryption)
+	}
+	return err
+}
+
+// entry returns an unloaded Entry for the file named by path.
+func (j *journal) entry(path string) *Entry {
+	return &Entry{Path: path, root: j.root, layout: j.layout, key: j.key}
+}
+
+// entryFor returns an unloaded Entry for the date t.
+func (j *journal) entryFor(t time.Time) *Entry {
+	return j.entry(filepath.Join(j.root, j.layout.Path(t)))
+}
@@ -1,6 +1,7 @@
 package gurnel
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -14,36 +15,66 @@ import (
 )
 
 const (
-	entryFormat = "2006-01-02-Journal-Entry-for-Jan-2" + ".md"
-	entryRegex  = `\d{4}-\d{2}-\d{2}-Journal-Entry-for-\D{3}-\d{1,2}` + ".md"
-	wordRegex   = `\S+`
+	wordRegex = `\S+`
+
+	// dateArgFormat is the format of dates given as command arguments.
+	dateArgFormat = "2006-01-02"
 )
 
 // Entry represents a single journal entry.
 type Entry struct {
-	Seconds     uint16
+	// Seconds is the time spent writing as recorded by earlier versions.
+	// Load migrates it to TimeSpent.
+	Seconds     uint16 `yaml:",omitempty"`
+	TimeSpent   Duration
+	Sessions    []Session `yaml:",omitempty"`
 	LowMood     uint8
 	HighMood    uint8
 	AverageMood uint8
-	Body        []byte    `yaml:"-"`
-	Path        string    `yaml:"-"`
-	ModTime     time.Time `yaml:"-"`
+	Tags        []string `yaml:",omitempty"`
+	People      []string `yaml:",omitempty"`
+	Sentiment   float64  `yaml:",omitempty"`
+	Published   string   `yaml:",omitempty"`
+	TimeZone    string   `yaml:",omitempty"`
+	// TemplateLines are hashes of the lines of the template the entry was
+	// started from.
+	TemplateLines []string  `yaml:",omitempty"`
+	Body          []byte    `yaml:"-"`
+	Path          string    `yaml:"-"`
+	ModTime       time.Time `yaml:"-"`
+	Backups       int       `yaml:"-"`
+	root          string
+	layout        Layout
+	key           *[keySize]byte
+	sum           [sha256.Size]byte
+}
+
+// NewEntry reads the journal rooted at the directory named by dir and either
+// returns the existing Entry for t, or creates a new one at the location
+// given by layout if none exists. New entries are populated from the
+// journal's template, if it has one.
+func NewEntry(dir string, layout Layout, t time.Time) (*Entry, error) {
+	return (&journal{root: dir, layout: layoutOrDefault(layout)}).newEntry(t)
 }
 
-// NewEntry reads the directory named by dir and either returns an existing
-// Entry in that directory, or creates a new one if none exist.
-func NewEntry(dir string, t time.Time) (*Entry, error) {
-	info, err := os.Stat(dir)
+func (j *journal) newEntry(t time.Time) (*Entry, error) {
+	info, err := os.Stat(j.root)
 	if err != nil {
 		return nil, err
 	}
 	if !info.IsDir() {
 		return nil, errors.New("must be a directory")
 	}
-	p := &Entry{Path: dir + string(filepath.Separator) + t.Format(entryFormat)}
+	p := j.entryFor(t)
 	_, err = os.Stat(p.Path)
 	if os.IsNotExist(err) {
-		p.ModTime = t
+		if err = os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+			return nil, err
+		}
+		if p.Body, err = renderTemplate(j, t); err != nil {
+			return nil, err
+		}
+		p.TemplateLines = templateLines(p.Body)
 		err = p.Save()
 	} else if err == nil {
 		_, err = p.Load()
@@ -51,7 +82,21 @@ func NewEntry(dir string, t time.Time) (*Entry, error) {
 	return p, err
 }
 
-// Load reads the file named by p.Path and populates the Entry
+// openEntry loads the existing Entry for t from the journal.
+func (j *journal) openEntry(t time.Time) (*Entry, error) {
+	p := j.entryFor(t)
+	if _, err := p.Load(); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no entry for %s", t.Format(dateArgFormat))
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Load reads the file named by p.Path and populates the Entry. modified
+// reports whether the file's contents differ from when the Entry was last
+// loaded or saved.
 func (p *Entry) Load() (modified bool, err error) {
 	f, err := os.Open(p.Path)
 	if err != nil {
@@ -66,35 +111,104 @@ func (p *Entry) Load() (modified bool, err error) {
 	if err != nil {
 		return false, err
 	}
-	modified = info.ModTime() != p.ModTime
-	p.ModTime = info.ModTime()
+	sum := sha256.Sum256(data)
+	modified = sum != p.sum
+	// Start over from the fields that aren't stored in the file, so that
+	// frontmatter removed from the file doesn't linger
+	*p = Entry{
+		Path:    p.Path,
+		ModTime: info.ModTime(),
+		Backups: p.Backups,
+		root:    p.root,
+		layout:  p.layout,
+		key:     p.key,
+		sum:     sum,
+	}
 	p.Body, err = frontmatter.Unmarshal(data, p)
+	if err == nil {
+		p.Body, err = decryptBody(p.key, p.Body)
+	}
+	if p.Seconds != 0 {
+		p.TimeSpent += Duration(time.Duration(p.Seconds) * time.Second)
+		p.Seconds = 0
+	}
 	return modified, err
 }
 
-// Save writes the Entry to the file named by p.Path
-func (p *Entry) Save() (err error) {
-	fm, err := frontmatter.Marshal(&p)
+// Save atomically writes the Entry to the file named by p.Path, first
+// backing up the previous version if p.Backups is nonzero. If the write
+// fails, Save returns a *SaveError.
+func (p *Entry) Save() error {
+	data, err := p.marshal(p.key)
 	if err != nil {
 		return err
 	}
-	var perm os.FileMode = 0666
-	if err = ioutil.WriteFile(p.Path, append(fm, p.Body...), perm); err != nil {
-		fmt.Println("Dump:")
-		fmt.Println(string(fm))
-		fmt.Println(string(p.Body))
+	if err = backupFile(p.journalRoot(), p.Path, p.Backups); err != nil {
+		err = fmt.Errorf("backing up entry: %w", err)
+	} else {
+		err = writeFileAtomic(p.Path, data)
+	}
+	if err != nil {
+		saveErr := &SaveError{Err: err}
+		if recovery, recErr := writeRecoveryFile(filepath.Base(p.Path), data); recErr == nil {
+			saveErr.RecoveryPath = recovery
+		}
+		return saveErr
+	}
+	p.sum = sha256.Sum256(data)
+	return nil
+}
+
+// marshal returns the contents of the Entry's file, with the body encrypted
+// if key is non-nil.
+func (p *Entry) marshal(key *[keySize]byte) ([]byte, error) {
+	fm, err := frontmatter.Marshal(&p)
+	if err != nil {
+		return nil, err
+	}
+	body := p.Body
+	if key != nil {
+		if body, err = encryptBody(key, body); err != nil {
+			return nil, fmt.Errorf("encrypting entry: %w", err)
+		}
 	}
-	return err
+	return append(fm, body...), nil
+}
+
+// AddSession records s in the Entry's session log and adds its active time
+// to p.TimeSpent.
+func (p *Entry) AddSession(s Session) {
+	p.Sessions = append(p.Sessions, s)
+	p.TimeSpent += Duration(s.Active())
 }
 
-// Date returns the date of the entry
+// Date returns the date of the entry, according to its journal's layout
 func (p *Entry) Date() (time.Time, error) {
-	return time.Parse(entryFormat, filepath.Base(p.Path))
+	rel, err := filepath.Rel(p.journalRoot(), p.Path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return layoutOrDefault(p.layout).Date(rel)
 }
 
-// Words returns the number of words in p.body
+// journalRoot returns the root of the journal containing the entry. An Entry
+// not created by NewEntry or read from a journal is assumed to be in its
+// journal's root.
+func (p *Entry) journalRoot() string {
+	if p.root == "" {
+		return filepath.Dir(p.Path)
+	}
+	return p.root
+}
+
+// Words returns the words in p.Body, excluding any left unchanged from the
+// entry's template and the headings of its sections
 func (p *Entry) Words() [][]byte {
-	return regexp.MustCompile(wordRegex).FindAll(p.Body, -1)
+	body := stripSections(p.Body)
+	if len(p.TemplateLines) > 0 {
+		body = stripTemplate(body, p.TemplateLines)
+	}
+	return regexp.MustCompile(wordRegex).FindAll(body, -1)
 }
 
 // PromptForMetadata prints questions to w and sets the values of p based on values read from reader.
@@ -113,11 +227,6 @@ func (p *Entry) PromptForMetadata(reader io.Reader, w io.Writer) error {
 	return nil
 }
 
-// IsEntry returns true if path refers to a file with an Entry-like name, false otherwise.
-func IsEntry(path string) bool {
-	return regexp.MustCompile(entryRegex).MatchString(path)
-}
-
 func (p *Entry) setLowMood(rating uint8) {
 	p.LowMood = rating
 }
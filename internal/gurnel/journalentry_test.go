@@ -0,0 +1,82 @@
+package gurnel
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestEntryLoadModified(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		edits    []string
+		modified bool
+	}{
+		{
+			desc:     "with no edits",
+			edits:    []string{},
+			modified: false,
+		},
+		{
+			desc:     "with the file touched but unchanged",
+			edits:    []string{""},
+			modified: false,
+		},
+		{
+			desc:     "with one edit",
+			edits:    []string{"foo"},
+			modified: true,
+		},
+		{
+			desc:     "with several edits in the same second",
+			edits:    []string{"foo", " bar", " baz"},
+			modified: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			now := (&test.FixedClock{}).Now()
+
+			p, err := NewEntry(dir, DefaultLayout, now)
+			if err != nil {
+				t.Fatalf("creating entry: %s", err)
+			}
+			info, err := os.Stat(p.Path)
+			if err != nil {
+				t.Fatalf("reading file info: %s", err)
+			}
+
+			for _, edit := range tC.edits {
+				data, err := ioutil.ReadFile(p.Path)
+				if err != nil {
+					t.Fatalf("reading file: %s", err)
+				}
+				if err := ioutil.WriteFile(p.Path, append(data, edit...), 0600); err != nil {
+					t.Fatalf("writing file: %s", err)
+				}
+				// Simulate a filesystem with coarse timestamps, or an editor
+				// that preserves them.
+				mtime := info.ModTime().Truncate(time.Second)
+				if err := os.Chtimes(p.Path, mtime, mtime); err != nil {
+					t.Fatalf("setting file times: %s", err)
+				}
+			}
+
+			modified, err := p.Load()
+			if err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			if modified != tC.modified {
+				t.Fatalf("wrong modified value. expected %t. got %t", tC.modified, modified)
+			}
+			if modified, _ = p.Load(); modified {
+				t.Fatal("expected a second load to report the file unmodified")
+			}
+		})
+	}
+}
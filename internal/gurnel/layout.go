@@ -0,0 +1,60 @@
+package gurnel
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Layout describes where entries are kept within a journal, as a
+// slash-separated path relative to the journal's root written using the
+// reference time of package time. For example, "2006/01/2006-01-02.md" keeps
+// one directory per month.
+type Layout string
+
+// DefaultLayout keeps entries in the journal's root with Jekyll-compatible
+// names.
+const DefaultLayout Layout = "2006-01-02-Journal-Entry-for-Jan-2.md"
+
+// Path returns the path, relative to the journal's root, of the entry for t.
+func (l Layout) Path(t time.Time) string {
+	return filepath.FromSlash(t.Format(string(l)))
+}
+
+// Date returns the date of the entry at rel, a path relative to the
+// journal's root. A field that appears more than once in l, such as the
+// year in "2006/2006-01-02.md", must have the same value each time.
+func (l Layout) Date(rel string) (time.Time, error) {
+	rel = filepath.ToSlash(rel)
+	t, err := time.Parse(string(l), rel)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// time.Parse keeps the last value of a repeated field, so check that the
+	// date gives back the same path
+	if t.Format(string(l)) != rel {
+		return time.Time{}, fmt.Errorf("%s does not name the entry for %s", rel, t.Format(dateArgFormat))
+	}
+	return t, nil
+}
+
+// Match reports whether rel, a path relative to the journal's root, names an
+// entry.
+func (l Layout) Match(rel string) bool {
+	_, err := l.Date(rel)
+	return err == nil
+}
+
+// layoutOrDefault returns l, or DefaultLayout if l is empty.
+func layoutOrDefault(l Layout) Layout {
+	if l == "" {
+		return DefaultLayout
+	}
+	return l
+}
+
+// dayDate returns midnight UTC on the calendar day of t, matching the dates
+// of entries.
+func dayDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
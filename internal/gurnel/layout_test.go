@@ -0,0 +1,64 @@
+package gurnel
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLayout(t *testing.T) {
+	date := time.Date(2008, time.April, 2, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		layout Layout
+		path   string
+		others []string
+	}{
+		{
+			layout: DefaultLayout,
+			path:   "2008-04-02-Journal-Entry-for-Apr-2.md",
+			others: []string{
+				"README.md",
+				".2008-04-02-Journal-Entry-for-Apr-2.md.tmp123",
+				"2008-04-02-Journal-Entry-for-Apr-3.md",
+				"2008-04-02-Journal-Entry-for-May-2.md",
+			},
+		},
+		{
+			layout: "2006/01/2006-01-02.md",
+			path:   filepath.Join("2008", "04", "2008-04-02.md"),
+			others: []string{
+				"2008-04-02.md",
+				filepath.Join("2008", "04", "notes.md"),
+				filepath.Join("2008", "05", "2008-04-02.md"),
+				filepath.Join("2007", "04", "2008-04-02.md"),
+			},
+		},
+		{
+			layout: "content/journal/2006-01-02.md",
+			path:   filepath.Join("content", "journal", "2008-04-02.md"),
+			others: []string{filepath.Join("content", "posts", "2008-04-02.md")},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(string(tC.layout), func(t *testing.T) {
+			if path := tC.layout.Path(date); path != tC.path {
+				t.Fatalf("wrong path. expected %q. got %q", tC.path, path)
+			}
+			if !tC.layout.Match(tC.path) {
+				t.Fatalf("expected %q to match", tC.path)
+			}
+			parsed, err := tC.layout.Date(tC.path)
+			if err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			if !parsed.Equal(date) {
+				t.Fatalf("wrong date. expected %s. got %s", date, parsed)
+			}
+			for _, other := range tC.others {
+				if tC.layout.Match(other) {
+					t.Fatalf("expected %q not to match", other)
+				}
+			}
+		})
+	}
+}
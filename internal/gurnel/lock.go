@@ -0,0 +1,209 @@
+package gurnel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	lockDir = "locks"
+
+	// lockHeartbeat is how often a running session refreshes its lock.
+	lockHeartbeat = 30 * time.Second
+	// lockStaleAfter is how long a lock may go without a heartbeat before
+	// its session is considered dead.
+	lockStaleAfter = 4 * lockHeartbeat
+)
+
+// entryLock records which gurnel session is editing an entry.
+type entryLock struct {
+	PID       int
+	Host      string
+	Started   time.Time
+	Heartbeat time.Time
+	path      string
+	// stop and done control the heartbeat started by keepFresh
+	stop chan struct{}
+	done chan struct{}
+}
+
+// lockPath returns the path of the lock file for the entry at entryPath in
+// the journal rooted at root.
+func lockPath(root, entryPath string) string {
+	return filepath.Join(root, journalConfigDir, lockDir, journalRel(root, entryPath)+".lock")
+}
+
+// acquireLock creates a lock for the entry at entryPath. If another session
+// already holds one, acquireLock returns that session's lock as held and a
+// nil lock.
+func acquireLock(root, entryPath string, now time.Time) (lock, held *entryLock, err error) {
+	path := lockPath(root, entryPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting hostname: %w", err)
+	}
+	lock = &entryLock{
+		PID:       os.Getpid(),
+		Host:      host,
+		Started:   now,
+		Heartbeat: now,
+		path:      path,
+	}
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		held, err = readLock(path)
+		return nil, held, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating lock: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, nil, fmt.Errorf("writing lock: %w", err)
+	}
+	return lock, nil, f.Close()
+}
+
+func readLock(path string) (*entryLock, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading lock: %w", err)
+	}
+	l := &entryLock{path: path}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("parsing lock %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// stale reports whether the session holding l has died: either its process
+// no longer exists on this host, or it has stopped refreshing the lock.
+func (l *entryLock) stale(now time.Time) bool {
+	if host, err := os.Hostname(); err == nil && host == l.Host && !processAlive(l.PID) {
+		return true
+	}
+	return now.Sub(l.Heartbeat) > lockStaleAfter
+}
+
+// elapsed returns how long the session holding l was known to be running.
+func (l *entryLock) elapsed() time.Duration {
+	if d := l.Heartbeat.Sub(l.Started); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// owned checks that the lock file still belongs to the session holding l,
+// rather than to a session that broke it.
+func (l *entryLock) owned() error {
+	current, err := readLock(l.path)
+	if err != nil {
+		return err
+	}
+	if current.PID != l.PID || current.Host != l.Host || !current.Started.Equal(l.Started) {
+		return fmt.Errorf("lock was taken by %s", current)
+	}
+	return nil
+}
+
+// refresh records that the session holding l is still running.
+func (l *entryLock) refresh(now time.Time) error {
+	if err := l.owned(); err != nil {
+		return fmt.Errorf("refreshing lock: %w", err)
+	}
+	l.Heartbeat = now
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.path, data)
+}
+
+// keepFresh refreshes l every lockHeartbeat until it is released. Failures
+// are reported to w.
+func (l *entryLock) keepFresh(w io.Writer, c clock) {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go func() {
+		defer close(l.done)
+		heartbeat := time.NewTicker(lockHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-heartbeat.C:
+				if err := l.refresh(c.Now()); err != nil {
+					fmt.Fprintln(w, err)
+				}
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+}
+
+// release stops refreshing l and removes the lock file, unless another
+// session has since taken it.
+func (l *entryLock) release() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+	}
+	if err := l.owned(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("removing lock: %w", err)
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing lock: %w", err)
+	}
+	return nil
+}
+
+func (l *entryLock) String() string {
+	return fmt.Sprintf("process %d on %s, started %s",
+		l.PID, l.Host, l.Started.Format("Jan 2 15:04:05 MST"))
+}
+
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// swapFiles returns the names of any editor swap or lock files for the entry
+// at path, such as those left behind by Vim or Emacs.
+func swapFiles(path string) []string {
+	dir, base := filepath.Split(path)
+	var found []string
+	for _, name := range []string{
+		"." + base + ".swp",
+		"." + base + ".swo",
+		".#" + base,
+	} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+	return found
+}
@@ -0,0 +1,114 @@
+package gurnel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestAcquireLock(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	now := (&test.FixedClock{}).Now()
+	path := filepath.Join(dir, DefaultLayout.Path(now))
+
+	lock, held, err := acquireLock(dir, path, now)
+	if err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	if lock == nil || held != nil {
+		t.Fatalf("expected to acquire the lock. got lock %v held by %v", lock, held)
+	}
+
+	second, held, err := acquireLock(dir, path, now)
+	if err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	if second != nil {
+		t.Fatal("expected a second session not to acquire the lock")
+	}
+	if held == nil || held.PID != os.Getpid() {
+		t.Fatalf("expected the lock to be held by this process. got %v", held)
+	}
+
+	later := now.Add(time.Minute)
+	if err := lock.refresh(later); err != nil {
+		t.Fatalf("refreshing lock: %s", err)
+	}
+	if held, _ = readLock(lockPath(dir, path)); !held.Heartbeat.Equal(later) {
+		t.Fatalf("wrong heartbeat. expected %s. got %s", later, held.Heartbeat)
+	}
+	if d := held.elapsed(); d != time.Minute {
+		t.Fatalf("wrong elapsed time. expected %s. got %s", time.Minute, d)
+	}
+
+	if err := lock.release(); err != nil {
+		t.Fatalf("releasing lock: %s", err)
+	}
+	if lock, _, _ = acquireLock(dir, path, now); lock == nil {
+		t.Fatal("expected to acquire a released lock")
+	}
+}
+
+func TestLockStale(t *testing.T) {
+	now := (&test.FixedClock{}).Now()
+	host, _ := os.Hostname()
+
+	testCases := []struct {
+		desc  string
+		lock  entryLock
+		stale bool
+	}{
+		{
+			desc:  "with a live process",
+			lock:  entryLock{PID: os.Getpid(), Host: host, Heartbeat: now},
+			stale: false,
+		},
+		{
+			desc:  "with a recent heartbeat from another host",
+			lock:  entryLock{PID: 1, Host: host + "-other", Heartbeat: now.Add(-lockHeartbeat)},
+			stale: false,
+		},
+		{
+			desc:  "with an old heartbeat from another host",
+			lock:  entryLock{PID: 1, Host: host + "-other", Heartbeat: now.Add(-time.Hour)},
+			stale: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if stale := tC.lock.stale(now); stale != tC.stale {
+				t.Fatalf("wrong staleness. expected %t. got %t", tC.stale, stale)
+			}
+		})
+	}
+}
+
+func TestLockTakenByAnotherSession(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	now := (&test.FixedClock{}).Now()
+	path := filepath.Join(dir, DefaultLayout.Path(now))
+
+	lock, _, err := acquireLock(dir, path, now)
+	if err != nil {
+		t.Fatalf("acquiring lock: %s", err)
+	}
+	// Another session breaks the lock and takes it
+	held, _ := readLock(lock.path)
+	if err := held.release(); err != nil {
+		t.Fatalf("breaking lock: %s", err)
+	}
+	if _, _, err := acquireLock(dir, path, now.Add(time.Minute)); err != nil {
+		t.Fatalf("taking lock: %s", err)
+	}
+
+	test.CheckErr(t, "lock was taken by", lock.refresh(now.Add(time.Minute)))
+	test.CheckErr(t, "lock was taken by", lock.release())
+	if _, err := os.Stat(lock.path); err != nil {
+		t.Fatalf("expected the other session's lock to be kept. got %s", err)
+	}
+}
@@ -0,0 +1,124 @@
+package gurnel
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type migrateLayoutCmd struct {
+	from   string
+	dryRun bool
+}
+
+func (*migrateLayoutCmd) Name() string      { return "migrate-layout" }
+func (*migrateLayoutCmd) ShortHelp() string { return "Move entries to a new layout" }
+
+func (c *migrateLayoutCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.StringVar(&c.from, "from", "", "current layout, if not the configured one")
+	fs.BoolVar(&c.dryRun, "n", false, "show what would be moved without moving anything")
+	return fs
+}
+
+func (*migrateLayoutCmd) LongHelp() string {
+	return `Moves every entry in the journal to the path given by the layout argument,
+and commits the move. Layouts are paths written using Go's reference time,
+e.g. 2006/01/2006-01-02.md. Afterward, set Layout in your config to match.
+
+Use -n to preview the move, and -from if entries aren't currently kept in the
+configured layout.`
+}
+
+type move struct {
+	from, to string
+}
+
+func (c *migrateLayoutCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	if len(args) != 1 {
+		return errors.New("exactly one layout must be given")
+	}
+	to := Layout(args[0])
+	from := layoutOrDefault(conf.Layout)
+	if c.from != "" {
+		from = Layout(c.from)
+	}
+
+	wd, err := journalDir()
+	if err != nil {
+		return err
+	}
+
+	moves, err := planMoves(wd, from, to)
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		fmt.Fprintln(w, "No entries to move")
+		return nil
+	}
+
+	for _, m := range moves {
+		fmt.Fprintf(w, "%s -> %s\n", m.from, m.to)
+		if c.dryRun {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(wd, filepath.Dir(m.to)), 0755); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		if err := git(wd, "mv", m.from, m.to); err != nil {
+			return fmt.Errorf("moving %s: %w", m.from, err)
+		}
+	}
+	if c.dryRun {
+		return nil
+	}
+
+	if err := git(wd, "commit", "-m", fmt.Sprintf("Move entries to layout %s", to)); err != nil {
+		return fmt.Errorf("committing move: %w", err)
+	}
+	fmt.Fprintf(w, "Moved %d entries. Set Layout to %q in your config\n", len(moves), to)
+	return nil
+}
+
+// planMoves returns the moves, relative to root, needed to take each entry in
+// the journal from one layout to another.
+func planMoves(root string, from, to Layout) ([]move, error) {
+	done := make(chan struct{})
+	defer close(done)
+	paths, errc := walkFiles(done, root, from, dateRange{})
+
+	var moves []move
+	targets := make(map[string]string)
+	for path := range paths {
+		p := &Entry{Path: path, root: root, layout: from}
+		date, err := p.Date()
+		if err != nil {
+			return nil, err
+		}
+		m := move{from: journalRel(root, path), to: to.Path(date)}
+		if other, ok := targets[m.to]; ok {
+			return nil, fmt.Errorf("%s and %s would both move to %s", other, m.from, m.to)
+		}
+		targets[m.to] = m.from
+		if m.from == m.to {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(root, m.to)); err == nil {
+			return nil, fmt.Errorf("%s would overwrite %s", m.from, m.to)
+		}
+		moves = append(moves, m)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].from < moves[j].from
+	})
+	return moves, nil
+}
@@ -0,0 +1,88 @@
+package gurnel
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestMigrateLayout(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		dryRun bool
+		out    []string
+	}{
+		{
+			desc:   "with a dry run",
+			dryRun: true,
+			out: []string{
+				"2008-04-11-Journal-Entry-for-Apr-11.md -> " + filepath.Join("2008", "04", "11.md"),
+			},
+		},
+		{
+			desc: "with a migration",
+			out:  []string{"moved 2 entries"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			for _, args := range [][]string{
+				{"init", "-q"},
+				{"config", "user.name", "test"},
+				{"config", "user.email", "test@example.com"},
+			} {
+				if err := git(dir, args...); err != nil {
+					t.Fatalf("setting up repository: %s", err)
+				}
+			}
+
+			now := (&test.FixedClock{}).Now()
+			var paths []string
+			for i := 0; i < 2; i++ {
+				p, err := NewEntry(dir, DefaultLayout, now.AddDate(0, 0, -i))
+				if err != nil {
+					t.Fatalf("creating entry: %s", err)
+				}
+				paths = append(paths, p.Path)
+			}
+			if err := git(dir, append([]string{"add"}, paths...)...); err != nil {
+				t.Fatalf("adding entries: %s", err)
+			}
+			if err := git(dir, "commit", "-q", "-m", "entries"); err != nil {
+				t.Fatalf("committing entries: %s", err)
+			}
+
+			cmd := migrateLayoutCmd{dryRun: tC.dryRun}
+			out := bytes.Buffer{}
+			err := cmd.Run(&bytes.Buffer{}, &out, []string{"2006/01/02.md"}, &Config{})
+			if err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, tC.out, out.String())
+
+			for i, path := range paths {
+				_, err := os.Stat(path)
+				if tC.dryRun != (err == nil) {
+					t.Fatalf("wrong state for %s after migration: %v", path, err)
+				}
+				moved := filepath.Join(dir, "2008", "04", []string{"12.md", "11.md"}[i])
+				_, err = os.Stat(moved)
+				if tC.dryRun == (err == nil) {
+					t.Fatalf("wrong state for %s after migration: %v", moved, err)
+				}
+			}
+			if !tC.dryRun {
+				status, err := exec.Command("git", "-C", dir, "status", "--porcelain").Output()
+				if err != nil || len(status) != 0 {
+					t.Fatalf("expected a clean working tree. got %q (%v)", status, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package gurnel
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// excerptWords is how many words of a past entry are shown.
+const excerptWords = 40
+
+type onThisDayCmd struct{}
+
+func (*onThisDayCmd) Name() string       { return "onthisday" }
+func (*onThisDayCmd) ShortHelp() string  { return "Show what you wrote on this day in the past" }
+func (*onThisDayCmd) Flag() flag.FlagSet { return flag.FlagSet{} }
+
+func (*onThisDayCmd) LongHelp() string {
+	return `Shows excerpts of the entries written a week ago, a month ago, and on
+today's date in previous years, with their moods.
+
+Set OnThisDay in your config to see them after writing with start.`
+}
+
+func (*onThisDayCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+	found, err := onThisDay(j, conf.today())
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		fmt.Fprintln(w, "Nothing written on this day before")
+	}
+	printPastEntries(w, found)
+	return nil
+}
+
+// pastEntry is an entry resurfaced on a later day.
+type pastEntry struct {
+	label string
+	date  time.Time
+	entry *Entry
+}
+
+// resurfaceLabel reports whether an entry dated date should be resurfaced
+// on now, and if so, how long ago it was written. Entries are resurfaced a
+// week and a month later, and on the same date in each later year.
+func resurfaceLabel(date, now time.Time) (string, bool) {
+	y, m, d := date.Date()
+	ny, nm, nd := now.Date()
+	sameDay := func(t time.Time) bool {
+		ty, tm, td := t.Date()
+		return ty == y && tm == m && td == d
+	}
+	switch {
+	case sameDay(now.AddDate(0, 0, -7)):
+		return "1 week ago", true
+	// A month before the 31st isn't the 1st of this month
+	case sameDay(now.AddDate(0, -1, 0)) && d == nd:
+		return "1 month ago", true
+	case m == nm && d == nd && y < ny:
+		return fmt.Sprintf("%d %s ago", ny-y, plural(ny-y, "year")), true
+	}
+	return "", false
+}
+
+// onThisDay returns the entries in j to resurface on now, most recent first.
+func onThisDay(j *journal, now time.Time) ([]pastEntry, error) {
+	done := make(chan struct{})
+	defer close(done)
+	paths, errc := walkFiles(done, j.root, j.layout, dateRange{})
+	var found []pastEntry
+	for path := range paths {
+		p := j.entry(path)
+		date, err := p.Date()
+		if err != nil {
+			return nil, err
+		}
+		if label, ok := resurfaceLabel(date, now); ok {
+			found = append(found, pastEntry{label: label, date: date, entry: p})
+		}
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].date.After(found[j].date)
+	})
+	for _, past := range found {
+		if _, err := past.entry.Load(); err != nil {
+			return nil, fmt.Errorf("loading %s: %w", past.entry.Path, err)
+		}
+	}
+	return found, nil
+}
+
+// printPastEntries writes excerpts of found to w.
+func printPastEntries(w io.Writer, found []pastEntry) {
+	for i, past := range found {
+		if i > 0 {
+			fmt.Fprint(w, "\n")
+		}
+		p := past.entry
+		fmt.Fprintf(w, "%s (%s)", past.label, past.date.Format("Monday, January 2 2006"))
+		if p.AverageMood != 0 {
+			fmt.Fprint(w, ", "+moodSummary(p))
+		}
+		fmt.Fprintf(w, ":\n  %s\n", excerpt(p))
+	}
+}
+
+// moodSummary describes p's moods, such as "mood 3 (2-5)".
+func moodSummary(p *Entry) string {
+	if p.LowMood == 0 || p.HighMood == 0 {
+		return fmt.Sprintf("mood %d", p.AverageMood)
+	}
+	return fmt.Sprintf("mood %d (%d-%d)", p.AverageMood, p.LowMood, p.HighMood)
+}
+
+// excerpt returns the first words of p's text.
+func excerpt(p *Entry) string {
+	words := p.Words()
+	if len(words) == 0 {
+		return "(empty)"
+	}
+	if len(words) <= excerptWords {
+		return string(bytes.Join(words, []byte(" ")))
+	}
+	return string(bytes.Join(words[:excerptWords], []byte(" "))) + " …"
+}
@@ -0,0 +1,118 @@
+package gurnel
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestResurfaceLabel(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		date  string
+		now   string
+		label string
+	}{
+		{
+			desc:  "with a week ago",
+			date:  "2008-04-05",
+			now:   "2008-04-12",
+			label: "1 week ago",
+		},
+		{
+			desc:  "with a month ago",
+			date:  "2008-03-12",
+			now:   "2008-04-12",
+			label: "1 month ago",
+		},
+		{
+			desc: "with a month before a day that February lacks",
+			date: "2008-03-02",
+			now:  "2008-03-31",
+		},
+		{
+			desc:  "with a year ago",
+			date:  "2007-04-12",
+			now:   "2008-04-12",
+			label: "1 year ago",
+		},
+		{
+			desc:  "with several years ago",
+			date:  "2005-04-12",
+			now:   "2008-04-12",
+			label: "3 years ago",
+		},
+		{
+			desc: "with another day",
+			date: "2008-04-11",
+			now:  "2008-04-12",
+		},
+		{
+			desc: "with today",
+			date: "2008-04-12",
+			now:  "2008-04-12",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			date, _ := time.Parse(dateArgFormat, tC.date)
+			now, _ := time.Parse(dateArgFormat, tC.now)
+			label, ok := resurfaceLabel(date, now.Add(16*time.Hour))
+			if label != tC.label || ok != (tC.label != "") {
+				t.Fatalf("expected %q. got %q", tC.label, label)
+			}
+		})
+	}
+}
+
+func TestOnThisDay(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		dates []time.Time
+		out   []string
+	}{
+		{
+			desc: "with no past entries",
+			out:  []string{"nothing written on this day before"},
+		},
+		{
+			desc: "with past entries",
+			dates: []time.Time{
+				time.Date(2006, 4, 12, 9, 0, 0, 0, time.UTC),
+				time.Date(2008, 3, 12, 9, 0, 0, 0, time.UTC),
+				time.Date(2008, 4, 1, 9, 0, 0, 0, time.UTC),
+			},
+			out: []string{
+				"1 month ago (wednesday, march 12 2008), mood 4 (2-5):\n  written on 2008-03-12\n\n" +
+					"2 years ago (wednesday, april 12 2006), mood 4 (2-5):\n  written on 2006-04-12\n",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			for _, date := range tC.dates {
+				p, err := NewEntry(dir, DefaultLayout, date)
+				if err != nil {
+					t.Fatalf("creating entry: %s", err)
+				}
+				p.Body = []byte("written on " + date.Format(dateArgFormat))
+				p.LowMood, p.AverageMood, p.HighMood = 2, 4, 5
+				if err := p.Save(); err != nil {
+					t.Fatalf("saving entry: %s", err)
+				}
+			}
+
+			cmd := onThisDayCmd{}
+			out := bytes.Buffer{}
+			conf := Config{clock: &test.FixedClock{}}
+			if err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, tC.out, out.String())
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package gurnel
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mikeraimondi/frontmatter/v2"
+)
+
+const (
+	privateStart = "<!-- private -->"
+	privateEnd   = "<!-- /private -->"
+)
+
+// PublishConfig describes the site entries are published to.
+type PublishConfig struct {
+	// SiteDir is the root of the site.
+	SiteDir string
+	// Generator is either "jekyll" (the default) or "hugo".
+	Generator string
+	// PostDir is where posts are written, relative to SiteDir. It defaults
+	// to _posts for Jekyll and content/posts for Hugo.
+	PostDir string
+	// Layout is the page layout given to posts. It defaults to "post" for
+	// Jekyll, and is omitted for Hugo.
+	Layout string
+	// Tags are added to the tags of every post.
+	Tags []string
+}
+
+func (pc *PublishConfig) postDir() string {
+	switch {
+	case pc.PostDir != "":
+		return filepath.Join(pc.SiteDir, pc.PostDir)
+	case pc.Generator == "hugo":
+		return filepath.Join(pc.SiteDir, "content", "posts")
+	default:
+		return filepath.Join(pc.SiteDir, "_posts")
+	}
+}
+
+func (pc *PublishConfig) layout() string {
+	if pc.Layout == "" && pc.Generator != "hugo" {
+		return "post"
+	}
+	return pc.Layout
+}
+
+// post is the frontmatter of a published entry.
+type post struct {
+	Title  string
+	Date   string
+	Layout string   `yaml:",omitempty"`
+	Tags   []string `yaml:",omitempty"`
+}
+
+type publishCmd struct {
+	title string
+	tags  string
+	force bool
+}
+
+func (*publishCmd) Name() string      { return "publish" }
+func (*publishCmd) ShortHelp() string { return "Publish an entry to a Jekyll or Hugo site" }
+
+func (c *publishCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.StringVar(&c.title, "title", "", "post title")
+	fs.StringVar(&c.tags, "tags", "", "comma-separated post tags")
+	fs.BoolVar(&c.force, "f", false, "overwrite an existing post")
+	return fs
+}
+
+func (*publishCmd) LongHelp() string {
+	return `Copies the entry for the given date (YYYY-MM-DD, or "today") into the site
+configured in Publish.SiteDir, as a post with title, date, layout, and tags
+frontmatter.
+
+Moods and other private metadata are not published, and neither is anything
+between <!-- private --> and <!-- /private --> in the body. The post's title
+is taken from -title, or else the entry's first heading.`
+}
+
+func (c *publishCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	if len(args) != 1 {
+		return errors.New("exactly one date must be given")
+	}
+	if conf.Publish.SiteDir == "" {
+		return errors.New("no site configured. Set Publish.SiteDir in your config")
+	}
+	date, err := parseDateArg(args[0], conf.today())
+	if err != nil {
+		return err
+	}
+
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+	p, err := j.openEntry(date)
+	if err != nil {
+		return err
+	}
+
+	title, body := splitTitle(stripPrivate(p.Body))
+	if c.title != "" {
+		title = c.title
+	}
+	dateTitle := "Journal entry for " + date.Format("January 2, 2006")
+	if title == "" {
+		title = dateTitle
+	}
+	meta := post{
+		Title:  title,
+		Date:   date.Format(dateArgFormat),
+		Layout: conf.Publish.layout(),
+		Tags:   append(append([]string{}, p.Tags...), conf.Publish.Tags...),
+	}
+	for _, tag := range strings.Split(c.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			meta.Tags = append(meta.Tags, tag)
+		}
+	}
+	fm, err := frontmatter.Marshal(&meta)
+	if err != nil {
+		return err
+	}
+
+	dir := conf.Publish.postDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating post directory: %w", err)
+	}
+	// A title without Latin letters or digits leaves nothing to name the
+	// post by
+	slug := slugify(title)
+	if slug == "" {
+		slug = slugify(dateTitle)
+	}
+	path := filepath.Join(dir, date.Format(dateArgFormat)+"-"+slug+".md")
+	if _, err := os.Stat(path); err == nil && !c.force {
+		return fmt.Errorf("%s already exists. Use -f to overwrite it", path)
+	}
+	if err := writeFileAtomic(path, append(fm, body...)); err != nil {
+		return fmt.Errorf("writing post: %w", err)
+	}
+	fmt.Fprintf(w, "Published to %s\n", path)
+
+	if rel, err := filepath.Rel(conf.Publish.SiteDir, path); err == nil {
+		path = filepath.ToSlash(rel)
+	}
+	p.Published = path
+	if err := p.Save(); err != nil {
+		return fmt.Errorf("recording publication: %w", err)
+	}
+	return nil
+}
+
+// parseDateArg parses a date given as a command argument, relative to now.
+// Like the dates of entries, the result is midnight UTC.
+func parseDateArg(arg string, now time.Time) (time.Time, error) {
+	if arg == "today" {
+		return dayDate(now), nil
+	}
+	t, err := time.Parse(dateArgFormat, arg)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q. Dates look like %s", arg, dateArgFormat)
+	}
+	return t, nil
+}
+
+// stripPrivate returns body without its private sections. A private section
+// with no end marker runs to the end of body.
+func stripPrivate(body []byte) []byte {
+	var out []byte
+	for {
+		start := bytes.Index(body, []byte(privateStart))
+		if start < 0 {
+			return append(out, body...)
+		}
+		out = append(out, body[:start]...)
+		body = body[start+len(privateStart):]
+		end := bytes.Index(body, []byte(privateEnd))
+		if end < 0 {
+			return out
+		}
+		body = body[end+len(privateEnd):]
+	}
+}
+
+// splitTitle returns the text of the first line of body if it is a level one
+// Markdown heading, and the remainder of body. Otherwise, it returns body
+// unchanged.
+func splitTitle(body []byte) (title string, rest []byte) {
+	trimmed := bytes.TrimLeft(body, "\n")
+	line := trimmed
+	if i := bytes.IndexByte(trimmed, '\n'); i >= 0 {
+		line, rest = trimmed[:i], trimmed[i+1:]
+	}
+	if !bytes.HasPrefix(line, []byte("# ")) {
+		return "", body
+	}
+	return strings.TrimSpace(string(line[2:])), bytes.TrimLeft(rest, "\n")
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(s string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
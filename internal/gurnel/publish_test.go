@@ -0,0 +1,105 @@
+package gurnel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestPublish(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		body    string
+		cmd     publishCmd
+		publish PublishConfig
+		post    string
+		out     []string
+		absent  []string
+	}{
+		{
+			desc:    "with a heading for the title",
+			body:    "# A Fine Day\n\nfoo bar\n",
+			publish: PublishConfig{Tags: []string{"journal"}},
+			post:    filepath.Join("_posts", "2008-04-12-a-fine-day.md"),
+			out: []string{
+				"title: A Fine Day",
+				"layout: post",
+				"- journal",
+				"foo bar",
+			},
+			absent: []string{"# A Fine Day", "mood", "timespent"},
+		},
+		{
+			desc:    "with private sections",
+			body:    "public\n<!-- private -->\nsecret\n<!-- /private -->\nalso public\n<!-- private -->\nmore secrets",
+			cmd:     publishCmd{title: "Flagged", tags: "a, b"},
+			publish: PublishConfig{Generator: "hugo"},
+			post:    filepath.Join("content", "posts", "2008-04-12-flagged.md"),
+			out:     []string{"title: Flagged", "- a\n- b", "public", "also public"},
+			absent:  []string{"secret", "layout"},
+		},
+		{
+			desc:   "with a title that has no Latin letters",
+			body:   "# 日記\n\nfoo bar\n",
+			post:   filepath.Join("_posts", "2008-04-12-journal-entry-for-april-12-2008.md"),
+			out:    []string{"title: 日記", "foo bar"},
+			absent: []string{"# 日記"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			site, err := ioutil.TempDir("", "gurnel_site")
+			if err != nil {
+				t.Fatalf("creating site dir: %s", err)
+			}
+			defer os.RemoveAll(site)
+
+			clock := &test.FixedClock{}
+			p, err := NewEntry(dir, DefaultLayout, clock.Now())
+			if err != nil {
+				t.Fatalf("creating entry: %s", err)
+			}
+			p.Body = []byte(tC.body)
+			p.AverageMood = 4
+			if err := p.Save(); err != nil {
+				t.Fatalf("saving entry: %s", err)
+			}
+
+			tC.publish.SiteDir = site
+			conf := Config{Publish: tC.publish, clock: clock}
+			out := bytes.Buffer{}
+			if err := tC.cmd.Run(&bytes.Buffer{}, &out, []string{"2008-04-12"}, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, []string{"published to"}, out.String())
+
+			data, err := ioutil.ReadFile(filepath.Join(site, tC.post))
+			if err != nil {
+				t.Fatalf("reading post: %s", err)
+			}
+			test.CheckOutput(t, tC.out, string(data))
+			for _, absent := range tC.absent {
+				if strings.Contains(string(data), absent) {
+					t.Fatalf("expected post not to contain %q. got %q", absent, data)
+				}
+			}
+
+			if _, err := p.Load(); err != nil {
+				t.Fatalf("reloading entry: %s", err)
+			}
+			if p.Published != filepath.ToSlash(tC.post) {
+				t.Fatalf("wrong publication record. expected %q. got %q", tC.post, p.Published)
+			}
+
+			err = tC.cmd.Run(&bytes.Buffer{}, &out, []string{"2008-04-12"}, &conf)
+			test.CheckErr(t, "already exists", err)
+		})
+	}
+}
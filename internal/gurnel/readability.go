@@ -0,0 +1,104 @@
+package gurnel
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// readability is what readability and style measures are computed from.
+// Words are counted without punctuation, so "Yes." and "yes" are the same
+// word, and tokens without any letters or digits aren't words at all.
+type readability struct {
+	words       int
+	sentences   int
+	syllables   int
+	uniqueWords int
+}
+
+// measureReadability counts the words, sentences, and syllables in words.
+// A sentence ends with a word ending in ".", "!", "?", or an ellipsis, or at
+// the end of the text.
+func measureReadability(words [][]byte) readability {
+	var r readability
+	unique := make(map[string]bool)
+	var open bool
+	for _, token := range words {
+		if word := normalizeWord(token); word != "" {
+			r.words++
+			r.syllables += syllables(word)
+			unique[word] = true
+			open = true
+		}
+		if open && endsSentence(token) {
+			r.sentences++
+			open = false
+		}
+	}
+	if open {
+		r.sentences++
+	}
+	r.uniqueWords = len(unique)
+	return r
+}
+
+func (r *readability) add(o readability) {
+	r.words += o.words
+	r.sentences += o.sentences
+	r.syllables += o.syllables
+}
+
+// gradeLevel returns the Flesch-Kincaid grade level: roughly, the years of
+// schooling needed to follow the text.
+func (r readability) gradeLevel() float64 {
+	if r.words == 0 || r.sentences == 0 {
+		return 0
+	}
+	return 0.39*r.sentenceLength() + 11.8*float64(r.syllables)/float64(r.words) - 15.59
+}
+
+// sentenceLength returns the average number of words in a sentence.
+func (r readability) sentenceLength() float64 {
+	if r.sentences == 0 {
+		return 0
+	}
+	return float64(r.words) / float64(r.sentences)
+}
+
+// typeTokenRatio returns the number of distinct words over the number of
+// words, from 0 to 1. Higher ratios mean a more varied vocabulary.
+func (r readability) typeTokenRatio() float64 {
+	if r.words == 0 {
+		return 0
+	}
+	return float64(r.uniqueWords) / float64(r.words)
+}
+
+func endsSentence(token []byte) bool {
+	s := strings.TrimRight(string(token), `"')]*_”’`)
+	if strings.HasSuffix(s, "…") {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r == '.' || r == '!' || r == '?'
+}
+
+// syllables estimates the number of syllables in the lowercase word by
+// counting groups of vowels, less a silent final "e".
+func syllables(word string) int {
+	var count int
+	var prevVowel bool
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+	if count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
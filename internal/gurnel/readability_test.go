@@ -0,0 +1,73 @@
+package gurnel
+
+import (
+	"testing"
+)
+
+func TestMeasureReadability(t *testing.T) {
+	testCases := []struct {
+		desc string
+		text string
+		want readability
+	}{
+		{
+			desc: "with no text",
+		},
+		{
+			desc: "with an unfinished sentence",
+			text: "the cat sat",
+			want: readability{words: 3, sentences: 1, syllables: 3, uniqueWords: 3},
+		},
+		{
+			desc: "with several sentences",
+			text: `Yes. "Really?" Yes, the table is ready…`,
+			want: readability{words: 7, sentences: 3, syllables: 10, uniqueWords: 6},
+		},
+		{
+			desc: "with stray punctuation",
+			text: "Wait — what ?",
+			want: readability{words: 2, sentences: 1, syllables: 2, uniqueWords: 2},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := Entry{Body: []byte(tC.text)}
+			if got := measureReadability(p.Words()); got != tC.want {
+				t.Fatalf("expected %+v. got %+v", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	testCases := []struct {
+		word string
+		want int
+	}{
+		{"cat", 1},
+		{"make", 1},
+		{"table", 2},
+		{"journal", 2},
+		{"beautiful", 3},
+		{"rhythm", 1},
+		{"42", 1},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.word, func(t *testing.T) {
+			if got := syllables(tC.word); got != tC.want {
+				t.Fatalf("expected %d syllables. got %d", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestGradeLevel(t *testing.T) {
+	r := readability{words: 100, sentences: 5, syllables: 150}
+	want := 0.39*20 + 11.8*1.5 - 15.59
+	if got := r.gradeLevel(); got != want {
+		t.Fatalf("expected %f. got %f", want, got)
+	}
+	if got := (readability{}).gradeLevel(); got != 0 {
+		t.Fatalf("expected 0 for no words. got %f", got)
+	}
+}
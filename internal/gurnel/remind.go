@@ -0,0 +1,251 @@
+package gurnel
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultRemindTime is when reminders are sent, unless configured
+	// otherwise.
+	defaultRemindTime = "20:00"
+	// remindUnit names the systemd units installed for reminders.
+	remindUnit = "gurnel-remind"
+	// cronMarker ends the crontab line installed for reminders, so that it
+	// can be replaced.
+	cronMarker = "# gurnel remind"
+)
+
+// RemindConfig describes how to be reminded to write.
+type RemindConfig struct {
+	// Time is the time of day, as HH:MM, that an installed reminder runs. It
+	// defaults to 20:00.
+	Time string
+	// Notify is "desktop" (the default) for a notification through
+	// notify-send, "bell" to ring the terminal bell, or "command" to run
+	// Command.
+	Notify string
+	// Command is run by the shell with the reminder in $GURNEL_MESSAGE.
+	Command string
+}
+
+// timeOfDay returns the hour and minute reminders are sent.
+func (rc *RemindConfig) timeOfDay() (hour, minute int, err error) {
+	s := rc.Time
+	if s == "" {
+		s = defaultRemindTime
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid reminder time %q. Use HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// notify sends the reminder message, writing it to w as well.
+func (rc *RemindConfig) notify(w io.Writer, message string) error {
+	switch rc.Notify {
+	case "", "desktop":
+		fmt.Fprintln(w, message)
+		// #nosec
+		out, err := exec.Command("notify-send", "--app-name=gurnel", "Gurnel", message).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("sending notification: %w: %s", err, bytes.TrimSpace(out))
+		}
+		return nil
+	case "bell":
+		fmt.Fprintf(w, "\a%s\n", message)
+		return nil
+	case "command":
+		if rc.Command == "" {
+			return errors.New("no reminder command configured. Set Remind.Command in your config")
+		}
+		fmt.Fprintln(w, message)
+		// #nosec
+		cmd := exec.Command("sh", "-c", rc.Command)
+		cmd.Env = append(os.Environ(), "GURNEL_MESSAGE="+message)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("running reminder command: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown reminder type %q", rc.Notify)
+	}
+}
+
+type remindCmd struct {
+	install bool
+	cron    bool
+}
+
+func (*remindCmd) Name() string      { return "remind" }
+func (*remindCmd) ShortHelp() string { return "Remind you to write if today's entry is missing" }
+
+func (c *remindCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.BoolVar(&c.install, "install", false, "run this every day at Remind.Time")
+	fs.BoolVar(&c.cron, "cron", false, "install a crontab line rather than a systemd user timer")
+	return fs
+}
+
+func (*remindCmd) LongHelp() string {
+	return `Sends a reminder if today's entry hasn't been written, or is short of the
+minimum word count. Remind.Notify chooses how: "desktop" for a notification
+through notify-send, "bell" to ring the terminal bell, or "command" to run
+Remind.Command with the reminder in $GURNEL_MESSAGE.
+
+With -install, writes a systemd user timer that checks the journal in this
+directory every day at Remind.Time (20:00 by default). With -cron as well,
+adds a line to your crontab instead. Desktop notifications from cron need
+DISPLAY and DBUS_SESSION_BUS_ADDRESS set in the crontab.`
+}
+
+func (c *remindCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	if c.install {
+		return c.installReminder(w, conf)
+	}
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+	p := j.entryFor(conf.today())
+	var message string
+	if _, err := p.Load(); os.IsNotExist(err) {
+		message = "You haven't written today's journal entry yet"
+	} else if err != nil {
+		return err
+	} else if words := len(p.Words()); words < conf.MinimumWordCount {
+		message = fmt.Sprintf("Today's journal entry has %d of %d words", words, conf.MinimumWordCount)
+	}
+	if message == "" {
+		fmt.Fprintln(w, "Today's entry is done")
+		return nil
+	}
+	return conf.Remind.notify(w, message)
+}
+
+func (c *remindCmd) installReminder(w io.Writer, conf *Config) error {
+	hour, minute, err := conf.Remind.timeOfDay()
+	if err != nil {
+		return err
+	}
+	root, err := journalDir()
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding gurnel: %w", err)
+	}
+	if c.cron {
+		return installCron(w, cronLine(hour, minute, root, exe))
+	}
+	return installTimer(w, hour, minute, root, exe)
+}
+
+// cronLine returns the crontab line that reminds at hour:minute for the
+// journal rooted at root, using the gurnel binary exe.
+func cronLine(hour, minute int, root, exe string) string {
+	return fmt.Sprintf("%d %d * * * cd %s && %s remind %s", minute, hour,
+		cronEscape(shellQuote(root)), cronEscape(shellQuote(exe)), cronMarker)
+}
+
+// cronEscape escapes the percent signs in s, which cron would otherwise turn
+// into line breaks, passing what follows the first to the command as input.
+func cronEscape(s string) string {
+	return strings.ReplaceAll(s, "%", `\%`)
+}
+
+// installCron adds line to the user's crontab, replacing any reminder
+// installed before.
+func installCron(w io.Writer, line string) error {
+	// crontab -l fails when there's no crontab yet
+	// #nosec
+	current, _ := exec.Command("crontab", "-l").Output()
+	var lines []string
+	for _, l := range strings.Split(strings.TrimRight(string(current), "\n"), "\n") {
+		if l != "" && !strings.HasSuffix(l, cronMarker) {
+			lines = append(lines, l)
+		}
+	}
+	lines = append(lines, line)
+	// #nosec
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("installing crontab: %w: %s", err, bytes.TrimSpace(out))
+	}
+	fmt.Fprintf(w, "Added to crontab: %s\n", line)
+	return nil
+}
+
+// installTimer writes a systemd user service and timer that remind at
+// hour:minute for the journal rooted at root, using the gurnel binary exe.
+func installTimer(w io.Writer, hour, minute int, root, exe string) error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(configDir, "systemd", "user")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	units := []struct{ name, text string }{
+		{remindUnit + ".service", fmt.Sprintf(`[Unit]
+Description=Remind to write in the journal at %s
+
+[Service]
+Type=oneshot
+WorkingDirectory=%s
+ExecStart=%s remind
+`, systemdEscape(root), systemdEscape(root), systemdQuote(exe))},
+		{remindUnit + ".timer", fmt.Sprintf(`[Unit]
+Description=Daily journal reminder
+
+[Timer]
+OnCalendar=*-*-* %02d:%02d:00
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, hour, minute)},
+	}
+	for _, unit := range units {
+		path := filepath.Join(dir, unit.name)
+		if err := ioutil.WriteFile(path, []byte(unit.text), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Wrote %s\n", path)
+	}
+	fmt.Fprintf(w, "Enable it with: systemctl --user daemon-reload && systemctl --user enable --now %s.timer\n", remindUnit)
+	return nil
+}
+
+// systemdEscape escapes the specifiers, such as %h, that systemd would
+// otherwise expand in s.
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote quotes s as a single argument of a systemd command line, such
+// as ExecStart=.
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "$$").Replace(systemdEscape(s))
+	return `"` + s + `"`
+}
+
+// shellQuote quotes s for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
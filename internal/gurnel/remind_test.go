@@ -0,0 +1,163 @@
+package gurnel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestRemind(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		bodies   []string
+		template string
+		remind   RemindConfig
+		err      string
+		out      []string
+	}{
+		{
+			desc:   "with no entry today",
+			remind: RemindConfig{Notify: "bell"},
+			out:    []string{"\ayou haven't written today's journal entry yet"},
+		},
+		{
+			desc:   "with a short entry",
+			bodies: []string{"one two three"},
+			remind: RemindConfig{Notify: "command", Command: `echo "got: $GURNEL_MESSAGE"`},
+			out:    []string{"got: today's journal entry has 3 of 5 words"},
+		},
+		{
+			desc:   "with a finished entry",
+			bodies: []string{"one two three four five"},
+			remind: RemindConfig{Notify: "bell"},
+			out:    []string{"today's entry is done"},
+		},
+		{
+			desc:     "with only the template written",
+			bodies:   []string{"## What went well?\n## What didn't?\n"},
+			template: "## What went well?\n## What didn't?\n",
+			remind:   RemindConfig{Notify: "bell"},
+			out:      []string{"\atoday's journal entry has 0 of 5 words"},
+		},
+		{
+			desc:   "with no command",
+			remind: RemindConfig{Notify: "command"},
+			err:    "no reminder command",
+		},
+		{
+			desc:   "with an unknown type",
+			remind: RemindConfig{Notify: "pigeon"},
+			err:    "unknown reminder type",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			if tC.template != "" {
+				tmplDir := filepath.Join(dir, journalConfigDir, templateDir)
+				if err := os.MkdirAll(tmplDir, 0700); err != nil {
+					t.Fatalf("creating template dir: %s", err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(tmplDir, defaultTemplate), []byte(tC.template), 0600); err != nil {
+					t.Fatalf("writing template: %s", err)
+				}
+			}
+			writeTestEntries(t, dir, tC.bodies...)
+
+			cmd := remindCmd{}
+			out := bytes.Buffer{}
+			conf := Config{
+				MinimumWordCount: 5,
+				Remind:           tC.remind,
+				clock:            &test.FixedClock{},
+			}
+			err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf)
+			test.CheckErr(t, tC.err, err)
+			test.CheckOutput(t, tC.out, out.String())
+		})
+	}
+}
+
+func TestRemindInstall(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	configDir := filepath.Join(dir, "config")
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", configDir)
+
+	cmd := remindCmd{install: true}
+	out := bytes.Buffer{}
+	conf := Config{Remind: RemindConfig{Time: "21:05"}}
+	if err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	test.CheckOutput(t, []string{"systemctl --user enable --now gurnel-remind.timer"}, out.String())
+
+	timer, err := ioutil.ReadFile(filepath.Join(configDir, "systemd", "user", "gurnel-remind.timer"))
+	if err != nil {
+		t.Fatalf("reading timer: %s", err)
+	}
+	test.CheckOutput(t, []string{"OnCalendar=*-*-* 21:05:00"}, string(timer))
+	service, err := ioutil.ReadFile(filepath.Join(configDir, "systemd", "user", "gurnel-remind.service"))
+	if err != nil {
+		t.Fatalf("reading service: %s", err)
+	}
+	exe, _ := os.Executable()
+	test.CheckOutput(t, []string{"WorkingDirectory=" + dir, "ExecStart=" + systemdQuote(exe) + " remind\n"}, string(service))
+}
+
+func TestSystemdQuote(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want string
+	}{
+		{"/usr/bin/gurnel", `"/usr/bin/gurnel"`},
+		{"/opt/my tools/gurnel", `"/opt/my tools/gurnel"`},
+		{`/opt/100%/"$HOME"\gurnel`, `"/opt/100%%/\"$$HOME\"\\gurnel"`},
+	}
+	for _, tC := range testCases {
+		if got := systemdQuote(tC.s); got != tC.want {
+			t.Fatalf("expected %s. got %s", tC.want, got)
+		}
+	}
+}
+
+func TestCronLine(t *testing.T) {
+	testCases := []struct {
+		desc string
+		root string
+		want string
+	}{
+		{
+			desc: "with a space in the path",
+			root: "/home/me/my journal",
+			want: "5 20 * * * cd '/home/me/my journal' && '/usr/bin/gurnel' remind # gurnel remind",
+		},
+		{
+			desc: "with a percent sign in the path",
+			root: "/home/me/100% journal",
+			want: `5 20 * * * cd '/home/me/100\% journal' && '/usr/bin/gurnel' remind # gurnel remind`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := cronLine(20, 5, tC.root, "/usr/bin/gurnel"); got != tC.want {
+				t.Fatalf("expected %q. got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+func TestRemindTime(t *testing.T) {
+	for _, s := range []string{"8pm", "25:00"} {
+		rc := RemindConfig{Time: s}
+		if _, _, err := rc.timeOfDay(); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package gurnel
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// reviewDir is where reviews are kept, relative to the journal's root.
+	reviewDir = "reviews"
+	// reviewUnusualWords is how many unusually frequent words a review lists.
+	reviewUnusualWords = 10
+)
+
+type reviewCmd struct {
+	week  bool
+	month bool
+}
+
+func (*reviewCmd) Name() string      { return "review" }
+func (*reviewCmd) ShortHelp() string { return "Write a review of this week or month" }
+
+func (c *reviewCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.BoolVar(&c.week, "week", false, "review this week, from Monday to Sunday")
+	fs.BoolVar(&c.month, "month", false, "review this month")
+	return fs
+}
+
+func (*reviewCmd) LongHelp() string {
+	return `Opens a review of this week or month in the editor, to be committed like an
+entry written with start. A new review is started with links to the
+period's entries, their moods and word counts, its unusually frequent
+words, and the tags used. Reviews are kept in the reviews directory of the
+journal.`
+}
+
+func (c *reviewCmd) Run(r io.Reader, w io.Writer, args []string, conf *Config) error {
+	if c.week == c.month {
+		return errors.New("choose one of -week or -month")
+	}
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+	period := monthPeriod(conf.today())
+	if c.week {
+		period = weekPeriod(conf.today())
+	}
+
+	p := j.entry(filepath.Join(j.root, reviewDir, period.name+".md"))
+	opts := writeOptions{message: "Review " + period.name}
+	if _, err := p.Load(); os.IsNotExist(err) {
+		if err := newReview(j, p, period); err != nil {
+			return fmt.Errorf("starting review: %w", err)
+		}
+		// Otherwise a review abandoned now would keep this period's totals
+		// when it's next opened
+		opts.discard = true
+	} else if err != nil {
+		return err
+	}
+	return writeEntry(r, w, conf, j, p, opts)
+}
+
+// reviewPeriod is the span of days a review covers.
+type reviewPeriod struct {
+	// name names the review's file, such as "2008-W15" or "2008-04".
+	name     string
+	title    string
+	from, to time.Time
+}
+
+// weekPeriod returns the week, from Monday to Sunday, containing t.
+func weekPeriod(t time.Time) reviewPeriod {
+	day := dayDate(t)
+	from := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	year, week := from.ISOWeek()
+	return reviewPeriod{
+		name:  fmt.Sprintf("%d-W%02d", year, week),
+		title: "Week of " + from.Format("January 2, 2006"),
+		from:  from,
+		to:    from.AddDate(0, 0, 6),
+	}
+}
+
+// monthPeriod returns the calendar month containing t.
+func monthPeriod(t time.Time) reviewPeriod {
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return reviewPeriod{
+		name:  from.Format("2006-01"),
+		title: from.Format("January 2006"),
+		from:  from,
+		to:    from.AddDate(0, 1, -1),
+	}
+}
+
+// newReview saves p as a new review of the entries in j within period. Its
+// moods are those of the entries combined.
+func newReview(j *journal, p *Entry, period reviewPeriod) error {
+	refFreqs, err := loadRefFreqs()
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "# %s\n\n## Entries\n\n", period.title)
+	var entries, words, moods, moodTotal int
+	wordMap := make(map[string]uint64)
+	tagCounts := make(map[string]int)
+	err = streamEntries(j, dateRange{from: period.from, to: period.to}, func(e *Entry) error {
+		date, err := e.Date()
+		if err != nil {
+			return err
+		}
+		link, err := filepath.Rel(filepath.Dir(p.Path), e.Path)
+		if err != nil {
+			return err
+		}
+		entryWords := e.Words()
+		fmt.Fprintf(&body, "- [%s](%s): %d %s", date.Format("Monday, January 2"),
+			filepath.ToSlash(link), len(entryWords), plural(len(entryWords), "word"))
+		if e.AverageMood != 0 {
+			fmt.Fprint(&body, ", "+moodSummary(e))
+			moods++
+			moodTotal += int(e.AverageMood)
+			if p.LowMood == 0 || (e.LowMood != 0 && e.LowMood < p.LowMood) {
+				p.LowMood = e.LowMood
+			}
+			if e.HighMood > p.HighMood {
+				p.HighMood = e.HighMood
+			}
+		}
+		fmt.Fprint(&body, "\n")
+
+		entries++
+		words += len(entryWords)
+		for _, word := range entryWords {
+			wordMap[strings.ToLower(string(word))]++
+		}
+		for _, tag := range e.allTags() {
+			tagCounts[tag]++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if entries == 0 {
+		fmt.Fprint(&body, "No entries.\n")
+	}
+
+	days := int(period.to.Sub(period.from).Hours()/24) + 1
+	fmt.Fprintf(&body, "\n## Totals\n\n%d of %d days written, %d %s in all.\n",
+		entries, days, words, plural(words, "word"))
+	if moods > 0 {
+		p.AverageMood = uint8(math.Round(float64(moodTotal) / float64(moods)))
+		fmt.Fprintf(&body, "Average mood %.1f", float64(moodTotal)/float64(moods))
+		if p.LowMood != 0 && p.HighMood != 0 {
+			fmt.Fprintf(&body, ", ranging from %d to %d", p.LowMood, p.HighMood)
+		}
+		fmt.Fprint(&body, ".\n")
+	}
+
+	if len(wordMap) > 0 {
+		fmt.Fprint(&body, "\n## Unusual words\n\n")
+		wordStats := unusualWords(wordMap, refFreqs)
+		if len(wordStats) > reviewUnusualWords {
+			wordStats = wordStats[:reviewUnusualWords]
+		}
+		for _, ws := range wordStats {
+			fmt.Fprintf(&body, "- %s (%.1fX)\n", ws.Word, ws.Frequency)
+		}
+	}
+
+	if len(tagCounts) > 0 {
+		tags := make([]string, 0, len(tagCounts))
+		for tag := range tagCounts {
+			tags = append(tags, tag)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			if tagCounts[tags[i]] != tagCounts[tags[j]] {
+				return tagCounts[tags[i]] > tagCounts[tags[j]]
+			}
+			return tags[i] < tags[j]
+		})
+		fmt.Fprint(&body, "\n## Tags\n\n")
+		for _, tag := range tags {
+			fmt.Fprintf(&body, "- #%s (%d)\n", tag, tagCounts[tag])
+		}
+	}
+	fmt.Fprint(&body, "\n## Reflections\n\n")
+
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+		return err
+	}
+	p.Body = []byte(body.String())
+	return p.Save()
+}
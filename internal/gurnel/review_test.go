@@ -0,0 +1,170 @@
+package gurnel
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestReviewPeriod(t *testing.T) {
+	now := (&test.FixedClock{}).Now()
+	testCases := []struct {
+		desc     string
+		period   reviewPeriod
+		name     string
+		from, to string
+	}{
+		{
+			desc:   "with a week",
+			period: weekPeriod(now),
+			name:   "2008-W15",
+			from:   "2008-04-07",
+			to:     "2008-04-13",
+		},
+		{
+			desc:   "with a week starting on a Sunday",
+			period: weekPeriod(time.Date(2008, 4, 13, 9, 0, 0, 0, time.UTC)),
+			name:   "2008-W15",
+			from:   "2008-04-07",
+			to:     "2008-04-13",
+		},
+		{
+			desc:   "with a month",
+			period: monthPeriod(now),
+			name:   "2008-04",
+			from:   "2008-04-01",
+			to:     "2008-04-30",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := tC.period
+			if p.name != tC.name || p.from.Format(dateArgFormat) != tC.from || p.to.Format(dateArgFormat) != tC.to {
+				t.Fatalf("expected %s from %s to %s. got %s from %s to %s",
+					tC.name, tC.from, tC.to, p.name, p.from.Format(dateArgFormat), p.to.Format(dateArgFormat))
+			}
+		})
+	}
+}
+
+func TestReview(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		cmd     reviewCmd
+		entries map[string]string
+		editor  string
+		file    string
+		err     string
+		review  []string
+	}{
+		{
+			desc: "with no period",
+			err:  "choose one",
+		},
+		{
+			desc: "with a week",
+			cmd:  reviewCmd{week: true},
+			entries: map[string]string{
+				"2008-04-06": "from last week",
+				"2008-04-08": "about #work",
+				"2008-04-11": "more #work and #home",
+			},
+			file: "2008-W15.md",
+			review: []string{
+				"averagemood: 3",
+				"# Week of April 7, 2008",
+				"- [Tuesday, April 8](../2008-04-08-Journal-Entry-for-Apr-8.md): 2 words, mood 3 (2-4)\n" +
+					"- [Friday, April 11](../2008-04-11-Journal-Entry-for-Apr-11.md): 4 words, mood 3 (2-4)\n",
+				"2 of 7 days written, 6 words in all.",
+				"average mood 3.0, ranging from 2 to 4.",
+				"- #work (2)\n- #home (1)",
+				"## Thoughts",
+			},
+		},
+		{
+			desc:    "with the new review left unchanged",
+			cmd:     reviewCmd{week: true},
+			entries: map[string]string{"2008-04-08": "about #work"},
+			editor:  "true",
+			file:    "2008-W15.md",
+		},
+		{
+			desc: "with a month",
+			cmd:  reviewCmd{month: true},
+			entries: map[string]string{
+				"2008-03-31": "before",
+				"2008-04-01": "one",
+				"2008-04-12": "two",
+			},
+			file: "2008-04.md",
+			review: []string{
+				"# April 2008",
+				"2 of 30 days written, 2 words in all.",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			for day, body := range tC.entries {
+				date, _ := time.Parse(dateArgFormat, day)
+				p, err := NewEntry(dir, DefaultLayout, date)
+				if err != nil {
+					t.Fatalf("creating entry: %s", err)
+				}
+				p.Body = []byte(body)
+				p.LowMood, p.AverageMood, p.HighMood = 2, 3, 4
+				if err := p.Save(); err != nil {
+					t.Fatalf("saving entry: %s", err)
+				}
+			}
+
+			out := bytes.Buffer{}
+			conf := Config{
+				Editor: "sed -i s/Reflections/Thoughts/",
+				clock:  &test.FixedClock{},
+			}
+			if tC.editor != "" {
+				conf.Editor = tC.editor
+			}
+			// Given a file, the editor shares it rather than reading ahead
+			in, err := ioutil.TempFile(dir, "stdin")
+			if err != nil {
+				t.Fatalf("creating input: %s", err)
+			}
+			defer in.Close()
+			if _, err := in.WriteString("n\n"); err != nil {
+				t.Fatalf("writing input: %s", err)
+			}
+			if _, err := in.Seek(0, io.SeekStart); err != nil {
+				t.Fatalf("rewinding input: %s", err)
+			}
+			err = tC.cmd.Run(in, &out, []string{}, &conf)
+			test.CheckErr(t, tC.err, err)
+			if tC.err != "" {
+				return
+			}
+			path := filepath.Join(dir, reviewDir, tC.file)
+			if tC.review == nil {
+				test.CheckOutput(t, []string{"aborting due to unchanged file"}, out.String())
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Fatalf("expected the review to be removed. got %v", err)
+				}
+				return
+			}
+			test.CheckOutput(t, []string{"exiting"}, out.String())
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading review: %s", err)
+			}
+			test.CheckOutput(t, tC.review, string(data))
+		})
+	}
+}
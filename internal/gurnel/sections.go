@@ -0,0 +1,32 @@
+package gurnel
+
+import (
+	"bytes"
+	"regexp"
+	"time"
+)
+
+// sectionHeading is the format of the heading that starts each section
+// appended to an entry with start -append.
+const sectionHeading = "## 15:04"
+
+var sectionRegex = regexp.MustCompile(`(?m)^## \d\d:\d\d[ \t]*(?:\n|$)`)
+
+// appendSection returns body with a new section for the time t at its end.
+func appendSection(body []byte, t time.Time) []byte {
+	out := append([]byte{}, bytes.TrimRight(body, "\n")...)
+	if len(out) > 0 {
+		out = append(out, "\n\n"...)
+	}
+	return append(out, t.Format(sectionHeading)+"\n\n"...)
+}
+
+// stripSections returns body without its section headings.
+func stripSections(body []byte) []byte {
+	return sectionRegex.ReplaceAll(body, nil)
+}
+
+// sections returns the number of sections in body.
+func sections(body []byte) int {
+	return len(sectionRegex.FindAll(body, -1))
+}
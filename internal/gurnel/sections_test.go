@@ -0,0 +1,51 @@
+package gurnel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendSection(t *testing.T) {
+	at := time.Date(2008, 4, 12, 21, 5, 0, 0, time.UTC)
+	testCases := []struct {
+		desc string
+		body string
+		want string
+	}{
+		{
+			desc: "with an empty entry",
+			want: "## 21:05\n\n",
+		},
+		{
+			desc: "with an entry",
+			body: "morning thoughts\n\n\n",
+			want: "morning thoughts\n\n## 21:05\n\n",
+		},
+		{
+			desc: "with a section",
+			body: "## 08:00\n\nmorning thoughts",
+			want: "## 08:00\n\nmorning thoughts\n\n## 21:05\n\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			body := []byte(tC.body)
+			if got := string(appendSection(body, at)); got != tC.want {
+				t.Fatalf("expected %q. got %q", tC.want, got)
+			}
+			if string(body) != tC.body {
+				t.Fatalf("body was changed to %q", body)
+			}
+		})
+	}
+}
+
+func TestSectionWords(t *testing.T) {
+	p := Entry{Body: []byte("## 08:00\n\nmorning thoughts\n\n## 21:05\nevening\n## Notes at 9:00\n")}
+	if got := len(p.Words()); got != 7 {
+		t.Fatalf("expected 7 words. got %d", got)
+	}
+	if got := sections(p.Body); got != 2 {
+		t.Fatalf("expected 2 sections. got %d", got)
+	}
+}
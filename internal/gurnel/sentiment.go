@@ -0,0 +1,194 @@
+package gurnel
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/mikeraimondi/gurnel/internal/bindata"
+)
+
+const (
+	// sentimentAsset is a lexicon of words scored from -4 (most negative) to
+	// 4 (most positive).
+	sentimentAsset = "sentiment.csv"
+	// sentimentAlpha controls how quickly the sum of word scores approaches
+	// the ends of the range -1 to 1, as in VADER.
+	sentimentAlpha = 15
+	// negationWindow is how many words after a negation like "not" are
+	// negated.
+	negationWindow = 3
+	// negationFactor scales the score of a negated word.
+	negationFactor = -0.74
+)
+
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "none": true, "nobody": true,
+	"nothing": true, "neither": true, "nor": true, "cannot": true, "without": true,
+}
+
+// lexicon maps words to their sentiment.
+type lexicon map[string]float64
+
+// loadLexicon reads the sentiment lexicon embedded in the binary.
+func loadLexicon() (lexicon, error) {
+	data, err := bindata.Asset(sentimentAsset)
+	if err != nil {
+		return nil, fmt.Errorf("loading asset: %w", err)
+	}
+	return parseLexicon(data)
+}
+
+func parseLexicon(data []byte) (lexicon, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = 2
+	lx := make(lexicon)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return lx, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		score, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sentiment score: %w", err)
+		}
+		lx[record[0]] = score
+	}
+}
+
+// score returns the sentiment of words, from -1 (most negative) to 1 (most
+// positive). Words shortly after a negation count against their usual
+// sentiment.
+func (lx lexicon) score(words [][]byte) float64 {
+	var sum float64
+	for i, word := range words {
+		v, ok := lx[normalizeWord(word)]
+		if !ok {
+			continue
+		}
+		for k := i - 1; k >= 0 && k >= i-negationWindow; k-- {
+			if isNegation(normalizeWord(words[k])) {
+				v *= negationFactor
+				break
+			}
+		}
+		sum += v
+	}
+	if sum == 0 {
+		return 0
+	}
+	return sum / math.Sqrt(sum*sum+sentimentAlpha)
+}
+
+// normalizeWord lowercases word and trims the punctuation around it.
+func normalizeWord(word []byte) string {
+	return strings.ToLower(strings.TrimFunc(string(word), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+func isNegation(word string) bool {
+	return negations[word] || strings.HasSuffix(word, "n't") || strings.HasSuffix(word, "n’t")
+}
+
+// roundScore rounds a sentiment score for storing in frontmatter.
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// sentimentPoint is the sentiment and mood of an entry.
+type sentimentPoint struct {
+	date      time.Time
+	sentiment float64
+	mood      uint8
+}
+
+// printSentiment reports the average sentiment of points, and how it tracks
+// the self-reported moods of those that have one, month by month.
+func printSentiment(w io.Writer, points []sentimentPoint) {
+	if len(points) == 0 {
+		return
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].date.Before(points[j].date)
+	})
+	var total float64
+	var xs, ys []float64
+	type month struct {
+		name               string
+		moods, sentiment   float64
+		moodCount, entries int
+	}
+	var months []*month
+	for _, pt := range points {
+		total += pt.sentiment
+		name := pt.date.Format("2006-01")
+		if len(months) == 0 || months[len(months)-1].name != name {
+			months = append(months, &month{name: name})
+		}
+		m := months[len(months)-1]
+		m.entries++
+		m.sentiment += pt.sentiment
+		if pt.mood != 0 {
+			m.moods += float64(pt.mood)
+			m.moodCount++
+			xs = append(xs, float64(pt.mood))
+			ys = append(ys, pt.sentiment)
+		}
+	}
+	fmt.Fprintf(w, "Average sentiment: %+.2f (from -1 to 1)\n", total/float64(len(points)))
+	if len(xs) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "Mood and sentiment by month:\n")
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, m := range months {
+		mood := "-"
+		if m.moodCount > 0 {
+			mood = fmt.Sprintf("%.1f", m.moods/float64(m.moodCount))
+		}
+		fmt.Fprintf(out, "%s\tmood %s\tsentiment %+.2f\n", m.name, mood, m.sentiment/float64(m.entries))
+	}
+	out.Flush()
+	if r, ok := correlation(xs, ys); ok {
+		fmt.Fprintf(w, "Correlation between mood and sentiment: %.2f\n", r)
+	}
+}
+
+// correlation returns the Pearson correlation coefficient of xs and ys. ok
+// is false if it is undefined.
+func correlation(xs, ys []float64) (r float64, ok bool) {
+	n := float64(len(xs))
+	if len(xs) < 2 {
+		return 0, false
+	}
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
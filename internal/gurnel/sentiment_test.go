@@ -0,0 +1,102 @@
+package gurnel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestLexiconScore(t *testing.T) {
+	lx, err := parseLexicon([]byte("bad,-3\ngood,3\nhappy,3\nsad,-2\n"))
+	if err != nil {
+		t.Fatalf("parsing lexicon: %s", err)
+	}
+
+	testCases := []struct {
+		desc  string
+		text  string
+		score float64
+	}{
+		{
+			desc: "with no scored words",
+			text: "the cat sat on the mat",
+		},
+		{
+			desc:  "with a positive word",
+			text:  "a Good day.",
+			score: 3 / math.Sqrt(9+sentimentAlpha),
+		},
+		{
+			desc:  "with mixed words",
+			text:  "happy but sad",
+			score: 1 / math.Sqrt(1+sentimentAlpha),
+		},
+		{
+			desc:  "with a negation",
+			text:  "I wasn't very happy",
+			score: -2.22 / math.Sqrt(2.22*2.22+sentimentAlpha),
+		},
+		{
+			desc:  "with a negation too far away",
+			text:  "not that it was all that bad",
+			score: -3 / math.Sqrt(9+sentimentAlpha),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := Entry{Body: []byte(tC.text)}
+			if got := lx.score(p.Words()); math.Abs(got-tC.score) > 1e-9 {
+				t.Fatalf("expected score %f. got %f", tC.score, got)
+			}
+		})
+	}
+}
+
+func TestParseLexicon(t *testing.T) {
+	testCases := []struct {
+		desc string
+		data string
+		err  string
+	}{
+		{
+			desc: "with a valid lexicon",
+			data: "good,3\nbad,-3\n",
+		},
+		{
+			desc: "with a missing score",
+			data: "good\n",
+			err:  "wrong number of fields",
+		},
+		{
+			desc: "with an invalid score",
+			data: "good,great\n",
+			err:  "invalid sentiment score",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := parseLexicon([]byte(tC.data))
+			test.CheckErr(t, tC.err, err)
+		})
+	}
+}
+
+func TestPrintSentiment(t *testing.T) {
+	day := time.Date(2008, 4, 12, 16, 0, 0, 0, time.UTC)
+	points := []sentimentPoint{
+		{date: day, sentiment: 0.5, mood: 8},
+		{date: day.AddDate(0, 0, -1), sentiment: -0.5, mood: 3},
+		{date: day.AddDate(0, -1, 0), sentiment: 0.25},
+	}
+	out := bytes.Buffer{}
+	printSentiment(&out, points)
+	test.CheckOutput(t, []string{
+		"average sentiment: +0.08",
+		"2008-03  mood -    sentiment +0.25",
+		"2008-04  mood 5.5  sentiment +0.00",
+		"correlation between mood and sentiment: 1.00",
+	}, out.String())
+}
@@ -0,0 +1,113 @@
+package gurnel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"time"
+)
+
+// Duration is a time.Duration that is written to frontmatter and config
+// files in its string form, e.g. "1h2m3s". A bare number is read as seconds.
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	if secs, err := strconv.ParseUint(string(text), 10, 64); err == nil {
+		*d = Duration(time.Duration(secs) * time.Second)
+		return nil
+	}
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, so that a bare number in a
+// JSON config file is read as seconds too.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) == 0 || data[0] != '"' {
+		return d.UnmarshalText(data)
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(text))
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).Round(time.Second).String()
+}
+
+// Session records a single editing session of an Entry.
+type Session struct {
+	Start       time.Time
+	End         time.Time
+	Words       int      `yaml:",omitempty"`
+	Idle        Duration `yaml:",omitempty"`
+	Interrupted bool     `yaml:",omitempty"`
+}
+
+// Active returns the time spent in the session, less any idle time.
+func (s *Session) Active() time.Duration {
+	return s.End.Sub(s.Start) - time.Duration(s.Idle)
+}
+
+// idleTracker polls a file during an editing session and accumulates the
+// time the file went unchanged beyond a timeout.
+type idleTracker struct {
+	path       string
+	timeout    time.Duration
+	lastChange time.Time
+	lastSum    [sha256.Size]byte
+	idle       time.Duration
+}
+
+func newIdleTracker(path string, timeout time.Duration, now time.Time) *idleTracker {
+	it := &idleTracker{path: path, timeout: timeout, lastChange: now}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		it.lastSum = sha256.Sum256(data)
+	}
+	return it
+}
+
+// poll checks the file for changes as of now.
+func (it *idleTracker) poll(now time.Time) {
+	data, err := ioutil.ReadFile(it.path)
+	if err != nil {
+		return
+	}
+	if sum := sha256.Sum256(data); sum != it.lastSum {
+		it.addGap(now)
+		it.lastSum = sum
+		it.lastChange = now
+	}
+}
+
+// finish polls the file a final time and returns the total idle time,
+// including any trailing period without changes.
+func (it *idleTracker) finish(now time.Time) time.Duration {
+	it.poll(now)
+	it.addGap(now)
+	it.lastChange = now
+	return it.idle
+}
+
+func (it *idleTracker) addGap(now time.Time) {
+	if gap := now.Sub(it.lastChange); it.timeout > 0 && gap > it.timeout {
+		it.idle += gap - it.timeout
+	}
+}
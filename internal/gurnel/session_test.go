@@ -0,0 +1,126 @@
+package gurnel
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestDurationUnmarshalText(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected time.Duration
+		err      string
+	}{
+		{"1h2m3s", time.Hour + 2*time.Minute + 3*time.Second, ""},
+		{"90", 90 * time.Second, ""},
+		{"0s", 0, ""},
+		{"soon", 0, "invalid duration"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.text, func(t *testing.T) {
+			var d Duration
+			err := d.UnmarshalText([]byte(tC.text))
+			test.CheckErr(t, tC.err, err)
+			if time.Duration(d) != tC.expected {
+				t.Fatalf("wrong duration. expected %s. got %s", tC.expected, time.Duration(d))
+			}
+		})
+	}
+}
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		json     string
+		expected time.Duration
+		err      string
+	}{
+		{`{"IdleTimeout": "5m"}`, 5 * time.Minute, ""},
+		{`{"IdleTimeout": 300}`, 5 * time.Minute, ""},
+		{`{"IdleTimeout": "300"}`, 5 * time.Minute, ""},
+		{`{"IdleTimeout": null}`, 0, ""},
+		{`{"IdleTimeout": 1.5}`, 0, "missing unit"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.json, func(t *testing.T) {
+			var conf Config
+			err := json.Unmarshal([]byte(tC.json), &conf)
+			test.CheckErr(t, tC.err, err)
+			if time.Duration(conf.IdleTimeout) != tC.expected {
+				t.Fatalf("wrong duration. expected %s. got %s", tC.expected, time.Duration(conf.IdleTimeout))
+			}
+		})
+	}
+}
+
+func TestEntryLoadMigratesSeconds(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "2008-04-12-Journal-Entry-for-Apr-12.md")
+	legacy := "---\nseconds: 65000\nlowmood: 2\n---\nfoo bar\n"
+	if err := ioutil.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatalf("writing entry: %s", err)
+	}
+
+	p := &Entry{Path: path}
+	for i := 0; i < 2; i++ {
+		if _, err := p.Load(); err != nil {
+			t.Fatalf("expected no error. got %s", err)
+		}
+		if expected := 65000 * time.Second; time.Duration(p.TimeSpent) != expected {
+			t.Fatalf("wrong time spent. expected %s. got %s", expected, time.Duration(p.TimeSpent))
+		}
+	}
+
+	p.AddSession(Session{
+		Start: time.Date(2008, time.April, 12, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2008, time.April, 12, 10, 0, 0, 0, time.UTC),
+		Idle:  Duration(15 * time.Minute),
+		Words: 2,
+	})
+	if err := p.Save(); err != nil {
+		t.Fatalf("saving entry: %s", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	test.CheckOutput(t, []string{"timespent: 18h48m20s", "idle: 15m0s", "lowmood: 2"}, string(data))
+	if _, err := p.Load(); err != nil {
+		t.Fatalf("reloading entry: %s", err)
+	}
+	if expected := 65000*time.Second + 45*time.Minute; time.Duration(p.TimeSpent) != expected {
+		t.Fatalf("wrong time spent. expected %s. got %s", expected, time.Duration(p.TimeSpent))
+	}
+	if len(p.Sessions) != 1 || p.Sessions[0].Words != 2 {
+		t.Fatalf("wrong sessions after reload. got %+v", p.Sessions)
+	}
+}
+
+func TestIdleTracker(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "entry.md")
+	if err := ioutil.WriteFile(path, []byte("start"), 0600); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	start := (&test.FixedClock{}).Now()
+	it := newIdleTracker(path, time.Minute, start)
+
+	// Unchanged for 10 minutes, then edited: 9 minutes idle.
+	it.poll(start.Add(5 * time.Minute))
+	ioutil.WriteFile(path, []byte("start edited"), 0600)
+	it.poll(start.Add(10 * time.Minute))
+	// Edited again within the timeout: no further idle time.
+	ioutil.WriteFile(path, []byte("start edited twice"), 0600)
+	it.poll(start.Add(10*time.Minute + 30*time.Second))
+	// Left open for 3 more minutes before quitting: 2 minutes idle.
+	idle := it.finish(start.Add(13*time.Minute + 30*time.Second))
+
+	if expected := 11 * time.Minute; idle != expected {
+		t.Fatalf("wrong idle time. expected %s. got %s", expected, idle)
+	}
+}
@@ -2,6 +2,7 @@ package gurnel
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -14,63 +15,198 @@ import (
 	"time"
 )
 
-type startCmd struct{}
+// idlePollInterval is how often the entry is checked for changes while the
+// editor is open, when idle detection is enabled.
+const idlePollInterval = 5 * time.Second
 
-func (*startCmd) Name() string       { return "start" }
-func (*startCmd) ShortHelp() string  { return "Begin journal entry for today" }
-func (*startCmd) Flag() flag.FlagSet { return flag.FlagSet{} }
+type startCmd struct {
+	append bool
+}
+
+func (*startCmd) Name() string      { return "start" }
+func (*startCmd) ShortHelp() string { return "Begin journal entry for today" }
+
+func (c *startCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.BoolVar(&c.append, "append", false, "add a new section, headed with the time, to today's entry")
+	return fs
+}
 
 func (*startCmd) LongHelp() string {
-	return "If you don't like the editor this uses, set $EDITOR to something else."
+	return `If you don't like the editor this uses, set $EDITOR to something else.
+
+To write more than once a day, use -append to start a new section of
+today's entry, headed with the time. Headings aren't counted as words.
+
+Entries are dated in the local time zone, or in TimeZone if it's set in your
+config, such as "America/New_York". If you write late, set DayRolloverHour
+to the hour your day ends: with 4, writing at 00:30 adds to the day before's
+entry.`
 }
 
-func (*startCmd) Run(r io.Reader, w io.Writer, args []string, conf *Config) error {
+func (c *startCmd) Run(r io.Reader, w io.Writer, args []string, conf *Config) error {
 	// Create or open entry at working directory
-	wd, err := os.Getwd()
+	j, err := openJournal(w, conf)
 	if err != nil {
-		return errors.New("getting working directory " + err.Error())
+		return err
 	}
-	wd, err = filepath.EvalSymlinks(wd)
+	p, err := j.newEntry(conf.today())
 	if err != nil {
-		return errors.New("evaluating symlinks " + err.Error())
+		return err
 	}
-	p, err := NewEntry(wd, conf.clock.Now())
+	opts := writeOptions{daily: true, message: "Done"}
+	if c.append {
+		opts.section = conf.now()
+	}
+	return writeEntry(r, w, conf, j, p, opts)
+}
+
+// writeOptions describes how an entry is written.
+type writeOptions struct {
+	// daily is set for daily entries, which must reach the minimum word
+	// count, are asked for moods, and are reported to Beeminder and webhooks
+	// once committed.
+	daily bool
+	// message is the commit message.
+	message string
+	// section, if set, is the time of a new section added to the entry
+	// before it's edited.
+	section time.Time
+	// discard is set for entries generated just before editing, which are
+	// removed if they're left unchanged.
+	discard bool
+}
+
+// writeEntry opens p in the editor and, if it was changed, saves it and
+// offers to commit it.
+func writeEntry(r io.Reader, w io.Writer, conf *Config, j *journal, p *Entry, opts writeOptions) error {
+	p.Backups = conf.Backups
+
+	// Take the entry's lock, so that concurrent sessions don't overwrite
+	// each other
+	lock, readOnly, err := lockEntry(r, w, j.root, p, conf)
 	if err != nil {
 		return err
 	}
+	if readOnly {
+		return viewReadOnly(r, w, p, conf)
+	}
+	if lock == nil {
+		fmt.Fprintln(w, "Aborting")
+		return nil
+	}
+	lock.keepFresh(w, conf.clock)
+	defer func() {
+		if err := lock.release(); err != nil {
+			fmt.Fprintln(w, err)
+		}
+	}()
 
-	// Open file for editing
-	editor := conf.Editor
-	if editor == "" {
-		editor = os.Getenv("EDITOR")
+	// Start a new section, which is taken out again if it isn't written in
+	var before []byte
+	if !opts.section.IsZero() {
+		before = p.Body
+		p.Body = appendSection(p.Body, opts.section)
+		if err := p.Save(); err != nil {
+			return errors.New("saving file " + err.Error())
+		}
 	}
-	editCmd := strings.Split(editor, " ")
-	editCmd = append(editCmd, p.Path)
-	startTime := conf.clock.Now()
-	// #nosec
-	cmd := exec.Command(editCmd[0], editCmd[1:]...)
-	cmd.Stdin = r
-	cmd.Stdout = w
-	cmd.Stderr = w
-	if err = cmd.Run(); err != nil {
-		return errors.New("opening editor " + err.Error())
+
+	// An encrypted entry is edited as a decrypted copy outside the journal
+	edit := p
+	if p.key != nil {
+		var wipe func()
+		if edit, wipe, err = p.decryptedCopy(); err != nil {
+			return err
+		}
+		defer wipe()
+	}
+	if err := runHooks(w, "pre-edit", conf.Hooks.PreEdit, j.root, p, edit); err != nil {
+		return err
+	}
+
+	// Open file for editing, watching for idle periods while the editor runs
+	session := Session{Start: conf.clock.Now()}
+	wordsBefore := len(p.Words())
+	idle := newIdleTracker(edit.Path, time.Duration(conf.IdleTimeout), session.Start)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var poll <-chan time.Time
+		if conf.IdleTimeout > 0 {
+			ticker := time.NewTicker(idlePollInterval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+		for {
+			select {
+			case <-poll:
+				idle.poll(conf.clock.Now())
+			case <-stop:
+				return
+			}
+		}
+	}()
+	err = runEditor(r, w, conf, edit.Path)
+	close(stop)
+	<-done
+	session.End = conf.clock.Now()
+	session.Idle = Duration(idle.finish(session.End))
+	if err != nil {
+		// Keep the time spent, and whatever was written, before the editor
+		// failed
+		session.Interrupted = true
+		if _, loadErr := edit.Load(); loadErr != nil {
+			return err
+		}
+		if edit != p {
+			p.adopt(edit)
+		}
+		p.AddSession(session)
+		if saveErr := p.Save(); saveErr != nil {
+			fmt.Fprintln(w, "saving file "+saveErr.Error())
+		}
+		return err
 	}
-	elapsed := time.Since(startTime)
 
-	// Abort if file is untouched
-	if modified, modErr := p.Load(); modErr != nil {
+	// Abort if file is unchanged, including by post-edit hooks
+	modified, modErr := edit.Load()
+	if modErr != nil {
 		return errors.New("loading file " + modErr.Error())
-	} else if !modified {
+	}
+	if len(conf.Hooks.PostEdit) > 0 {
+		if err := runHooks(w, "post-edit", conf.Hooks.PostEdit, j.root, p, edit); err != nil {
+			fmt.Fprintln(w, err)
+		}
+		hookModified, modErr := edit.Load()
+		if modErr != nil {
+			return errors.New("loading file " + modErr.Error())
+		}
+		modified = modified || hookModified
+	}
+	if !modified {
 		fmt.Fprintln(w, "Aborting due to unchanged file")
+		if opts.discard {
+			return os.Remove(p.Path)
+		}
+		if before != nil {
+			p.Body = before
+			return p.Save()
+		}
 		return nil
 	}
+	if edit != p {
+		p.adopt(edit)
+	}
 
 	// Check word count before proceeding to metadata collection
 	wordCount := len(p.Words())
 	fmt.Fprintf(w, "%v words in entry\n", wordCount)
-	if wordCount < conf.MinimumWordCount {
+	enough := !opts.daily || wordCount >= conf.MinimumWordCount
+	if !enough {
 		fmt.Fprintf(w, "Minimum word count is %v. Insufficient word count to commit\n", conf.MinimumWordCount)
-	} else {
+	} else if opts.daily {
 		fmt.Fprintf(w, "---begin entry preview---\n%v\n--end entry preview---\n", string(p.Body))
 
 		// Collect & set metadata
@@ -78,12 +214,39 @@ func (*startCmd) Run(r io.Reader, w io.Writer, args []string, conf *Config) erro
 			return errors.New("collecting metadata " + promptErr.Error())
 		}
 	}
-	p.Seconds += uint16(elapsed.Seconds())
+	session.Words = len(p.Words()) - wordsBefore
+	p.AddSession(session)
+	if p.TimeZone == "" {
+		p.TimeZone = conf.zoneName()
+	}
+	// Frontmatter is stored in plain text, so nothing is copied into it from
+	// an encrypted body
+	if opts.daily && conf.ExtractTags && p.key == nil {
+		p.ExtractTags()
+	}
+	if opts.daily && conf.StoreSentiment && p.key == nil {
+		lx, err := loadLexicon()
+		if err != nil {
+			return err
+		}
+		p.Sentiment = roundScore(lx.score(p.Words()))
+	}
 	if saveErr := p.Save(); saveErr != nil {
 		return errors.New("saving file " + saveErr.Error())
 	}
+	if opts.daily && conf.OnThisDay {
+		defer func() {
+			found, err := onThisDay(j, conf.today())
+			if err != nil {
+				fmt.Fprintln(w, err)
+			} else if len(found) > 0 {
+				fmt.Fprint(w, "\nOn this day\n\n")
+				printPastEntries(w, found)
+			}
+		}()
+	}
 
-	if wordCount < conf.MinimumWordCount {
+	if !enough {
 		return nil
 	}
 
@@ -95,32 +258,28 @@ func (*startCmd) Run(r io.Reader, w io.Writer, args []string, conf *Config) erro
 		input = strings.TrimSpace(input)
 		switch input {
 		case "y":
+			if err := runHooks(w, "pre-commit", conf.Hooks.PreCommit, j.root, p, p); err != nil {
+				return fmt.Errorf("not committing: %w", err)
+			}
+
 			// Commit the changes
-			// #nosec
-			err = exec.Command("git", "add", p.Path).Run()
+			err = git(j.root, "add", p.Path)
 			if err != nil {
 				return errors.New("adding file to version control " + err.Error())
 			}
-			err = exec.Command("git", "commit", "-m", "Done").Run()
+			err = git(j.root, "commit", "-m", opts.message)
 			if err != nil {
 				return errors.New("committing file " + err.Error())
 			}
 			fmt.Fprintln(w, "Committed")
-
-			token, err := ioutil.ReadFile(conf.BeeminderTokenFile)
-			if err != nil {
-				return fmt.Errorf("reading token: %w", err)
-			}
-			client, err := newBeeminderClient(conf.BeeminderUser, token)
-			if err != nil {
-				return fmt.Errorf("setting up client: %w", err)
-			}
-			err = client.postDatapoint(conf.BeeminderGoal, wordCount, conf.clock.Now())
-			if err != nil {
-				return fmt.Errorf("posting to Beeminder: %w", err)
+			if err := runHooks(w, "post-commit", conf.Hooks.PostCommit, j.root, p, p); err != nil {
+				fmt.Fprintln(w, err)
 			}
 
-			return nil
+			if !opts.daily {
+				return nil
+			}
+			return afterCommit(w, conf, p)
 		case "n":
 			fmt.Fprintln(w, "Exiting")
 			return nil
@@ -131,3 +290,153 @@ func (*startCmd) Run(r io.Reader, w io.Writer, args []string, conf *Config) erro
 	}
 	return scanner.Err()
 }
+
+// afterCommit reports the committed entry p to Beeminder, if enabled, and to
+// any webhooks. A failure to reach one doesn't keep p from the others, and is
+// recorded for gurnel status.
+func afterCommit(w io.Writer, conf *Config, p *Entry) error {
+	ctx := context.Background()
+	var errs []string
+	record := func(integration string, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		date, dateErr := p.Date()
+		if dateErr != nil {
+			return
+		}
+		day := date.Format(dateArgFormat)
+		if recErr := recordDelivery(p.journalRoot(), integration, day, conf.now(), err != nil); recErr != nil {
+			errs = append(errs, recErr.Error())
+		}
+	}
+	if conf.BeeminderEnabled {
+		record("Beeminder", postToBeeminder(ctx, w, conf, p))
+	}
+	if len(conf.Webhooks) > 0 {
+		record("webhooks", fireWebhooks(ctx, w, conf.Webhooks, "entry.committed", p))
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func postToBeeminder(ctx context.Context, w io.Writer, conf *Config, p *Entry) error {
+	client, err := conf.beeminderClient(w)
+	if err != nil {
+		return err
+	}
+	if err := reportEntry(ctx, client, conf.beeminderGoals(), p, conf.now()); err != nil {
+		return fmt.Errorf("posting to Beeminder: %w", err)
+	}
+	return nil
+}
+
+// lockEntry acquires the lock for p. Time spent in an interrupted session is
+// recovered into p. If a live session holds the lock, the user chooses
+// whether to view the entry read-only, break the lock, or abort; lockEntry
+// returns a nil lock in the latter case.
+func lockEntry(
+	r io.Reader,
+	w io.Writer,
+	root string,
+	p *Entry,
+	conf *Config,
+) (lock *entryLock, readOnly bool, err error) {
+	for _, name := range swapFiles(p.Path) {
+		fmt.Fprintf(w, "Found editor swap file %s. Another editor may have this entry open\n", name)
+	}
+
+	now := conf.clock.Now()
+	lock, held, err := acquireLock(root, p.Path, now)
+	if err != nil || lock != nil {
+		return lock, false, err
+	}
+
+	if held.stale(now) {
+		fmt.Fprintf(w, "Recovering interrupted session (%s)\n", held)
+		p.AddSession(Session{
+			Start:       held.Started,
+			End:         held.Started.Add(held.elapsed()),
+			Interrupted: true,
+		})
+		if err := p.Save(); err != nil {
+			return nil, false, fmt.Errorf("saving recovered time: %w", err)
+		}
+		return breakLock(root, p, held, now)
+	}
+
+	fmt.Fprintf(w, "Entry is locked by %s\n", held)
+	for {
+		fmt.Fprint(w, "View (r)ead-only, (b)reak lock, or (a)bort? ")
+		var input string
+		if _, err := fmt.Fscanf(r, "%s\n", &input); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, false, nil
+			}
+			fmt.Fprintln(w, "Unrecognized input")
+			continue
+		}
+		switch input {
+		case "r":
+			return nil, true, nil
+		case "b":
+			return breakLock(root, p, held, now)
+		case "a":
+			return nil, false, nil
+		default:
+			fmt.Fprintln(w, "Unrecognized input")
+		}
+	}
+}
+
+func breakLock(root string, p *Entry, held *entryLock, now time.Time) (*entryLock, bool, error) {
+	if err := held.release(); err != nil {
+		return nil, false, err
+	}
+	lock, held, err := acquireLock(root, p.Path, now)
+	if err == nil && lock == nil {
+		err = fmt.Errorf("entry was locked again by %s", held)
+	}
+	return lock, false, err
+}
+
+// viewReadOnly opens a read-only, decrypted copy of p in the editor. The copy
+// is wiped afterward.
+func viewReadOnly(r io.Reader, w io.Writer, p *Entry, conf *Config) error {
+	data, err := p.marshal(nil)
+	if err != nil {
+		return fmt.Errorf("reading entry: %w", err)
+	}
+	dir, err := privateTempDir()
+	if err != nil {
+		return fmt.Errorf("creating read-only copy: %w", err)
+	}
+	defer wipeDir(dir)
+	path := filepath.Join(dir, filepath.Base(p.Path))
+	if err := ioutil.WriteFile(path, data, 0400); err != nil {
+		return fmt.Errorf("writing read-only copy: %w", err)
+	}
+	return runEditor(r, w, conf, path)
+}
+
+// runEditor opens the file named by path in the configured editor, falling
+// back to $EDITOR, and waits for it to exit.
+func runEditor(r io.Reader, w io.Writer, conf *Config, path string) error {
+	editor := conf.Editor
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	editCmd := strings.Split(editor, " ")
+	editCmd = append(editCmd, path)
+	// #nosec
+	cmd := exec.Command(editCmd[0], editCmd[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return errors.New("opening editor " + err.Error())
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package gurnel
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mikeraimondi/gurnel/internal/test"
 )
@@ -34,11 +36,15 @@ func (tr *testReader) Read(p []byte) (int, error) {
 
 func TestStart(t *testing.T) {
 	testCases := []struct {
-		desc  string
-		input string
-		conf  Config
-		err   string
-		out   []string
+		desc   string
+		input  string
+		stdin  []string
+		lock   *entryLock
+		noEdit bool
+		append bool
+		conf   Config
+		err    string
+		out    []string
 	}{
 		{
 			desc:  "with input exceeding the minimum length",
@@ -56,6 +62,70 @@ func TestStart(t *testing.T) {
 			},
 			out: []string{"2 words", "Insufficient word count"},
 		},
+		{
+			desc:   "with the entry locked by a live session",
+			input:  "foo bar baz",
+			stdin:  []string{"a\n"},
+			noEdit: true,
+			lock: &entryLock{
+				PID:       os.Getpid(),
+				Host:      "localhost",
+				Started:   (&test.FixedClock{}).Now(),
+				Heartbeat: (&test.FixedClock{}).Now(),
+			},
+			conf: Config{
+				MinimumWordCount: 3,
+			},
+			out: []string{"locked by process", "aborting"},
+		},
+		{
+			desc:  "with the entry locked by an interrupted session",
+			input: "foo bar baz",
+			lock: &entryLock{
+				PID:       os.Getpid(),
+				Host:      "localhost",
+				Started:   (&test.FixedClock{}).Now().Add(-time.Hour),
+				Heartbeat: (&test.FixedClock{}).Now().Add(-time.Hour + time.Minute),
+			},
+			conf: Config{
+				MinimumWordCount: 3,
+			},
+			out: []string{"recovering interrupted session", "begin entry preview"},
+		},
+		{
+			desc:   "with a new section",
+			input:  "foo bar baz",
+			append: true,
+			conf: Config{
+				MinimumWordCount: 3,
+			},
+			out: []string{"3 words in entry", "## 16:00\n\nfoo bar baz"},
+		},
+		{
+			desc:  "with edit hooks",
+			input: "foo bar baz",
+			conf: Config{
+				MinimumWordCount: 3,
+				Hooks: HooksConfig{
+					PreEdit:  []string{"echo $GURNEL_HOOK with $GURNEL_WORDS words"},
+					PostEdit: []string{"echo $GURNEL_HOOK with $GURNEL_WORDS words"},
+				},
+			},
+			out: []string{"pre-edit with 0 words", "post-edit with 3 words", "exiting"},
+		},
+		{
+			desc:  "with a failing pre-commit hook",
+			input: "foo bar baz",
+			stdin: []string{"1\n", "1\n", "1\n", "1\n", "y\n"},
+			conf: Config{
+				MinimumWordCount: 3,
+				Hooks: HooksConfig{
+					PreCommit: []string{"echo misspelled; exit 1"},
+				},
+			},
+			err: "not committing",
+			out: []string{"misspelled"},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -68,10 +138,24 @@ func TestStart(t *testing.T) {
 			dir, cleanup := test.SetupTestDir(t)
 			defer cleanup()
 
-			cmd := startCmd{}
+			if tC.lock != nil {
+				path := lockPath(dir, filepath.Join(dir, DefaultLayout.Path(tC.conf.clock.Now())))
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatalf("creating lock directory: %s", err)
+				}
+				data, _ := json.Marshal(tC.lock)
+				if err := ioutil.WriteFile(path, data, 0600); err != nil {
+					t.Fatalf("writing lock: %s", err)
+				}
+			}
+
+			cmd := startCmd{append: tC.append}
+			if tC.stdin == nil {
+				tC.stdin = []string{"1\n", "1\n", "1\n", "1\n", "n\n"}
+			}
 			inReader := testReader{
 				t:     t,
-				input: []string{"1\n", "1\n", "1\n", "1\n", "n\n"},
+				input: tC.stdin,
 			}
 			out := bytes.Buffer{}
 			errC := make(chan error)
@@ -80,16 +164,31 @@ func TestStart(t *testing.T) {
 				errC <- cmd.Run(&inReader, &out, []string{}, &tC.conf)
 			}()
 
-			files, _ := ioutil.ReadDir(dir)
+			// Wait until this session holds the entry's lock, meaning the
+			// editor is about to open, unless the session won't edit at all
+			locked := func() bool {
+				l, err := readLock(lockPath(dir, filepath.Join(dir, DefaultLayout.Path(tC.conf.clock.Now()))))
+				return err == nil && l.Started.Equal(tC.conf.clock.Now())
+			}
+			// and, if appending, the new section has been added
+			sectionAdded := func() bool {
+				data, err := ioutil.ReadFile(filepath.Join(dir, DefaultLayout.Path(tC.conf.clock.Now())))
+				return err == nil && sections(data) > 0
+			}
 			var file os.FileInfo
-			for {
-				if len(files) == 1 {
-					file = files[0]
-					break
-				} else if len(files) > 1 {
-					t.Fatalf("expected 1 file in directory. got %d", len(files))
+			for file == nil || (!tC.noEdit && !locked()) || (tC.append && !sectionAdded()) {
+				files, _ := ioutil.ReadDir(dir)
+				var entries []os.FileInfo
+				for _, f := range files {
+					if DefaultLayout.Match(f.Name()) {
+						entries = append(entries, f)
+					}
+				}
+				if len(entries) == 1 {
+					file = entries[0]
+				} else if len(entries) > 1 {
+					t.Fatalf("expected 1 entry in directory. got %d", len(entries))
 				}
-				files, _ = ioutil.ReadDir(dir)
 			}
 
 			defer test.WriteFile(t, filepath.Join(dir, file.Name()), tC.input)()
@@ -101,3 +200,103 @@ func TestStart(t *testing.T) {
 		})
 	}
 }
+
+func TestStartEditorFailure(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+
+	editor := filepath.Join(dir, "failing_editor.sh")
+	script := "#!/bin/sh\necho written words >> \"$1\"\nexit 1\n"
+	if err := ioutil.WriteFile(editor, []byte(script), 0700); err != nil {
+		t.Fatalf("writing editor: %s", err)
+	}
+	conf := Config{Editor: editor, clock: &test.FixedClock{}}
+
+	out := bytes.Buffer{}
+	err := (&startCmd{}).Run(&bytes.Buffer{}, &out, []string{}, &conf)
+	test.CheckErr(t, "opening editor", err)
+
+	j := &journal{root: dir, layout: DefaultLayout}
+	p := j.entryFor(conf.clock.Now())
+	if _, err := p.Load(); err != nil {
+		t.Fatalf("loading entry: %s", err)
+	}
+	if len(p.Sessions) != 1 || !p.Sessions[0].Interrupted {
+		t.Fatalf("expected an interrupted session. got %+v", p.Sessions)
+	}
+	test.CheckOutput(t, []string{"written words"}, string(p.Body))
+}
+
+func TestStartEncrypted(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		conf   Config
+		out    []string
+		absent []string
+	}{
+		{
+			desc: "with edit hooks and a nested layout",
+			conf: Config{
+				Layout: "2006/01/02.md",
+				Hooks:  HooksConfig{PreEdit: []string{"echo date $GURNEL_DATE"}},
+			},
+			out: []string{"date 2008-04-12"},
+		},
+		{
+			desc:   "with tags extracted",
+			conf:   Config{ExtractTags: true},
+			absent: []string{"secret", "alice", "tags:", "people:"},
+		},
+		{
+			desc:   "with sentiment stored",
+			conf:   Config{StoreSentiment: true},
+			absent: []string{"sentiment:"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			os.Setenv(passphraseEnv, "correct horse")
+			defer os.Unsetenv(passphraseEnv)
+
+			editor := filepath.Join(dir, "editor.sh")
+			script := "#!/bin/sh\necho 'a happy #secret day with @alice' >> \"$1\"\n"
+			if err := ioutil.WriteFile(editor, []byte(script), 0700); err != nil {
+				t.Fatalf("writing editor: %s", err)
+			}
+			tC.conf.Editor = editor
+			tC.conf.Encryption.Enabled = true
+			tC.conf.clock = &test.FixedClock{}
+
+			// Given a file, the editor shares it rather than reading ahead
+			in, err := ioutil.TempFile(dir, "stdin")
+			if err != nil {
+				t.Fatalf("creating input: %s", err)
+			}
+			defer in.Close()
+			if _, err := in.WriteString("1\n1\n1\n1\nn\n"); err != nil {
+				t.Fatalf("writing input: %s", err)
+			}
+			if _, err := in.Seek(0, io.SeekStart); err != nil {
+				t.Fatalf("rewinding input: %s", err)
+			}
+
+			out := bytes.Buffer{}
+			if err := (&startCmd{}).Run(in, &out, []string{}, &tC.conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, append(tC.out, "exiting"), out.String())
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, layoutOrDefault(tC.conf.Layout).Path(tC.conf.clock.Now())))
+			if err != nil {
+				t.Fatalf("reading entry: %s", err)
+			}
+			for _, absent := range tC.absent {
+				if bytes.Contains(data, []byte(absent)) {
+					t.Fatalf("expected entry not to contain %q. got %q", absent, data)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package gurnel
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -20,21 +21,55 @@ import (
 	"github.com/mikeraimondi/gurnel/internal/bindata"
 )
 
-type statsCmd struct{}
+type statsCmd struct {
+	tag     string
+	person  string
+	json    bool
+	entries bool
+}
+
+func (*statsCmd) Name() string      { return "stats" }
+func (*statsCmd) ShortHelp() string { return "View journal statistics" }
 
-func (*statsCmd) Name() string       { return "stats" }
-func (*statsCmd) ShortHelp() string  { return "View journal statistics" }
-func (*statsCmd) Flag() flag.FlagSet { return flag.FlagSet{} }
+func (c *statsCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.StringVar(&c.tag, "tag", "", "only include entries with this tag")
+	fs.StringVar(&c.person, "person", "", "only include entries mentioning this person")
+	fs.BoolVar(&c.json, "json", false, "print the statistics as JSON")
+	fs.BoolVar(&c.entries, "entries", false, "also show the statistics of each entry")
+	return fs
+}
 
 func (*statsCmd) LongHelp() string {
 	return "Unusually frequent/infrequent words are relative " +
-		"to a Google Ngram corpus of scanned literature"
+		"to a Google Ngram corpus of scanned literature.\n\n" +
+		"Sentiment is scored from each entry's words with an offline lexicon, " +
+		"and compared with the average mood you reported month by month. " +
+		"StoreSentiment saves each entry's score to its frontmatter, except " +
+		"in encrypted journals.\n\n" +
+		"Readability is measured as the Flesch-Kincaid grade level, the " +
+		"average sentence length, and the type-token ratio: the share of " +
+		"words that are distinct. Use -entries for these measures of each " +
+		"entry, and -json for everything in JSON.\n\n" +
+		"Use -tag or -person to include only entries with a #tag or @mention."
+}
+
+// keep reports whether p matches the command's filters.
+func (c *statsCmd) keep(p *Entry) bool {
+	if c.tag != "" && !containsFold(p.allTags(), strings.TrimPrefix(c.tag, "#")) {
+		return false
+	}
+	if c.person != "" && !containsFold(p.allPeople(), strings.TrimPrefix(c.person, "@")) {
+		return false
+	}
+	return true
 }
 
-func (*statsCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+// loadRefFreqs reads the frequencies of words in the reference corpus.
+func loadRefFreqs() (map[string]float64, error) {
 	refFreqsCSV, err := bindata.Asset("eng-us-10000-1960.csv")
 	if err != nil {
-		return fmt.Errorf("loading asset: %w", err)
+		return nil, fmt.Errorf("loading asset: %w", err)
 	}
 	csvReader := csv.NewReader(bytes.NewReader(refFreqsCSV))
 	csvReader.FieldsPerRecord = 2
@@ -46,54 +81,98 @@ func (*statsCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) erro
 			break
 		}
 		if csvErr != nil {
-			return csvErr
+			return nil, csvErr
 		}
 
 		if record[0] == "" || record[1] == "" {
-			return fmt.Errorf("invalid input")
+			return nil, fmt.Errorf("invalid input")
 		}
 		freq, csvErr := strconv.ParseFloat(record[1], 64)
 		if csvErr != nil {
-			return fmt.Errorf("invalid frequency: %w", csvErr)
+			return nil, fmt.Errorf("invalid frequency: %w", csvErr)
 		}
 		refFreqs[strings.ReplaceAll(record[0], `"`, `\"`)] = freq
 	}
+	return refFreqs, nil
+}
+
+// unusualWords returns the words counted in wordMap, from the most
+// unusually frequent to the most unusually infrequent relative to refFreqs.
+func unusualWords(wordMap map[string]uint64, refFreqs map[string]float64) []*wordStat {
+	var wordCount uint64
+	for _, count := range wordMap {
+		wordCount += count
+	}
+	wordStats := make([]*wordStat, len(wordMap))
+	i := 0
+	for word, count := range wordMap {
+		frequency := float64(count) / float64(wordCount)
+		var relFrequency float64
+		refFrequency := refFreqs[word]
+		if frequency > refFrequency {
+			if refFrequency > 0 {
+				relFrequency = frequency / refFrequency
+			}
+		} else {
+			relFrequency = (refFrequency / frequency) * -1
+		}
+		wordStats[i] = &wordStat{Word: word, Frequency: relFrequency, occurrences: count}
+		i++
+	}
+
+	sort.Slice(wordStats, func(i, j int) bool {
+		return wordStats[i].Frequency > wordStats[j].Frequency
+	})
+	return wordStats
+}
+
+func (c *statsCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	refFreqs, err := loadRefFreqs()
+	if err != nil {
+		return err
+	}
 
-	wd, err := os.Getwd()
+	lx, err := loadLexicon()
 	if err != nil {
-		return errors.New("getting working directory " + err.Error())
+		return err
 	}
-	wd, err = filepath.EvalSymlinks(wd)
+
+	j, err := openJournal(w, conf)
 	if err != nil {
-		return errors.New("evaluating symlinks " + err.Error())
+		return err
 	}
 
 	done := make(chan struct{})
 	defer close(done)
-	paths, errc := walkFiles(done, wd)
-	c := make(chan result)
+	paths, errc := walkFiles(done, j.root, j.layout, dateRange{})
+	results := make(chan result)
 	var wg sync.WaitGroup
 	const numScanners = 32
 	wg.Add(numScanners)
 	for i := 0; i < numScanners; i++ {
 		go func() {
-			entryScanner(done, paths, c)
+			entryScanner(done, j, paths, c.keep, lx, results)
 			wg.Done()
 		}()
 	}
 	go func() {
 		wg.Wait()
-		close(c)
+		close(results)
 	}()
-	var entryCount float64
+	var entries []result
+	var timeSpent, idle time.Duration
+	var style readability
 	wordMap := make(map[string]uint64)
-	t := conf.clock.Now()
+	t := conf.today()
 	minDate := t
-	for r := range c {
+	for r := range results {
 		if r.err != nil {
 			return r.err
 		}
-		entryCount++
+		entries = append(entries, r)
+		timeSpent += r.timeSpent
+		idle += r.idle
+		style.add(r.readability)
 		for word, count := range r.wordMap {
 			wordMap[word] += count
 		}
@@ -106,81 +185,248 @@ func (*statsCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) erro
 		return err
 	}
 
-	if entryCount == 0 {
+	if len(entries) == 0 {
+		if c.json {
+			return json.NewEncoder(w).Encode(statsReport{Entries: []entryStats{}})
+		}
 		fmt.Fprint(w, "no entries found! why not try writing one with 'gurnel start'?")
 		return nil
 	}
 
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].date.Before(entries[j].date)
+	})
+	growth, vocabulary := vocabularyGrowth(entries)
+	style.uniqueWords = vocabulary
+
+	entryCount := float64(len(entries))
 	percent := entryCount / math.Ceil(t.Sub(minDate).Hours()/24)
-	const outFormat = "Jan 2 2006"
-	fmt.Fprintf(w, "%.2f%% of days journaled since %v\n", percent*100, minDate.Format(outFormat))
 	var wordCount uint64
 	for _, count := range wordMap {
 		wordCount += count
 	}
+
+	wordStats := unusualWords(wordMap, refFreqs)
+	topUnusualWordCount := 100
+	if topUnusualWordCount > len(wordStats) {
+		topUnusualWordCount = len(wordStats)
+	}
+	frequent := wordStats[:topUnusualWordCount]
+	infrequent := make([]*wordStat, topUnusualWordCount)
+	for i := range infrequent {
+		infrequent[i] = wordStats[len(wordStats)-1-i]
+	}
+
+	if c.json {
+		report := statsReport{
+			Since:             minDate.Format(dateArgFormat),
+			DaysJournaled:     percent * 100,
+			Words:             wordCount,
+			AverageWords:      float64(wordCount) / entryCount,
+			Seconds:           int(timeSpent.Seconds()),
+			IdleSeconds:       int(idle.Seconds()),
+			GradeLevel:        style.gradeLevel(),
+			SentenceLength:    style.sentenceLength(),
+			TypeTokenRatio:    style.typeTokenRatio(),
+			VocabularyGrowth:  growth,
+			UnusuallyFrequent: frequent,
+			UnusuallyRare:     infrequent,
+			Entries:           make([]entryStats, len(entries)),
+		}
+		var moods, sentiments []float64
+		for i, e := range entries {
+			report.Sentiment += e.sentiment / entryCount
+			report.Entries[i] = entryStats{
+				Date:           e.date.Format(dateArgFormat),
+				Words:          e.readability.words,
+				Sentences:      e.readability.sentences,
+				Sections:       e.sections,
+				GradeLevel:     e.readability.gradeLevel(),
+				SentenceLength: e.readability.sentenceLength(),
+				TypeTokenRatio: e.readability.typeTokenRatio(),
+				Sentiment:      e.sentiment,
+				AverageMood:    e.mood,
+			}
+			if e.mood != 0 {
+				moods = append(moods, float64(e.mood))
+				sentiments = append(sentiments, e.sentiment)
+			}
+		}
+		if r, ok := correlation(moods, sentiments); ok {
+			report.MoodCorrelation = &r
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	const outFormat = "Jan 2 2006"
+	fmt.Fprintf(w, "%.2f%% of days journaled since %v\n", percent*100, minDate.Format(outFormat))
 	fmt.Fprintf(w, "Total word count: %v\n", wordCount)
 	avgCount := float64(wordCount) / entryCount
 	fmt.Fprintf(w, "Average word count: %.1f\n", avgCount)
+	fmt.Fprintf(w, "Total time writing: %v", Duration(timeSpent))
+	if idle > 0 {
+		fmt.Fprintf(w, " (excluding %v idle)", Duration(idle))
+	}
 	fmt.Fprint(w, "\n")
-
-	wordStats := make([]*wordStat, len(wordMap))
-	i := 0
-	for word, count := range wordMap {
-		frequency := float64(count) / float64(wordCount)
-		var relFrequency float64
-		refFrequency := refFreqs[word]
-		if frequency > refFrequency {
-			if refFrequency > 0 {
-				relFrequency = frequency / refFrequency
-			}
-		} else {
-			relFrequency = (refFrequency / frequency) * -1
+	if minutes := timeSpent.Minutes(); minutes >= 1 {
+		fmt.Fprintf(w, "Average words per minute: %.1f\n", float64(wordCount)/minutes)
+	}
+	var multiple int
+	for _, e := range entries {
+		if e.sections > 1 {
+			multiple++
 		}
-		wordStats[i] = &wordStat{word: word, occurrences: count, frequency: relFrequency}
-		i++
 	}
+	if multiple > 0 {
+		fmt.Fprintf(w, "Days written in several sections: %d\n", multiple)
+	}
+	fmt.Fprintf(w, "Reading grade level: %.1f (Flesch-Kincaid)\n", style.gradeLevel())
+	fmt.Fprintf(w, "Average sentence length: %.1f words\n", style.sentenceLength())
+	fmt.Fprintf(w, "Lexical diversity: %.2f (type-token ratio)\n", style.typeTokenRatio())
+	sentiments := make([]sentimentPoint, len(entries))
+	for i, e := range entries {
+		sentiments[i] = sentimentPoint{date: e.date, sentiment: e.sentiment, mood: e.mood}
+	}
+	printSentiment(w, sentiments)
+	fmt.Fprint(w, "\n")
 
-	sort.Slice(wordStats, func(i, j int) bool {
-		return wordStats[i].frequency > wordStats[j].frequency
-	})
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(out, "New words by month:\n")
+	for _, m := range growth {
+		fmt.Fprintf(out, "%s\t%d\n", m.Month, m.NewWords)
+	}
+	out.Flush()
+	fmt.Fprint(w, "\n")
 
-	var topUnusualWordCount uint64
-	topUnusualWordCount = 100
-	if topUnusualWordCount > wordCount {
-		topUnusualWordCount = wordCount
+	if c.entries {
+		fmt.Fprint(out, "Date\tWords\tSentences\tGrade\tTTR\tSentiment\n")
+		for _, e := range entries {
+			fmt.Fprintf(out, "%s\t%d\t%d\t%.1f\t%.2f\t%+.2f\n",
+				e.date.Format(dateArgFormat),
+				e.readability.words,
+				e.readability.sentences,
+				e.readability.gradeLevel(),
+				e.readability.typeTokenRatio(),
+				e.sentiment,
+			)
+		}
+		out.Flush()
+		fmt.Fprint(w, "\n")
 	}
-	out := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+
+	out = tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
 	fmt.Fprintf(out, "Top %v unusually frequent words:\n", topUnusualWordCount)
-	for _, ws := range wordStats[:topUnusualWordCount] {
-		fmt.Fprintf(out, "%v\t%.1fX\n", ws.word, ws.frequency)
+	for _, ws := range frequent {
+		fmt.Fprintf(out, "%v\t%.1fX\n", ws.Word, ws.Frequency)
 	}
 	out.Flush()
 	fmt.Fprint(out, "\n")
 	fmt.Fprintf(out, "Top %v unusually infrequent words:\n", topUnusualWordCount)
-	for i := 1; i <= int(topUnusualWordCount); i++ {
-		ws := wordStats[len(wordStats)-i]
-		fmt.Fprintf(out, "%v\t%.1fX\n", ws.word, ws.frequency)
+	for _, ws := range infrequent {
+		fmt.Fprintf(out, "%v\t%.1fX\n", ws.Word, ws.Frequency)
 	}
 	out.Flush()
 
 	return nil
 }
 
+// vocabularyGrowth returns the number of words first used in each month of
+// entries, which are in date order, and the number of distinct words in all
+// of them.
+func vocabularyGrowth(entries []result) (growth []vocabularyMonth, vocabulary int) {
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		month := e.date.Format("2006-01")
+		if len(growth) == 0 || growth[len(growth)-1].Month != month {
+			growth = append(growth, vocabularyMonth{Month: month})
+		}
+		for token := range e.wordMap {
+			if word := normalizeWord([]byte(token)); word != "" && !seen[word] {
+				seen[word] = true
+				growth[len(growth)-1].NewWords++
+			}
+		}
+	}
+	return growth, len(seen)
+}
+
 type result struct {
-	wordMap map[string]uint64
-	date    time.Time
-	err     error
+	wordMap     map[string]uint64
+	date        time.Time
+	timeSpent   time.Duration
+	idle        time.Duration
+	readability readability
+	sections    int
+	sentiment   float64
+	mood        uint8
+	err         error
 }
 
 type wordStat struct {
-	word        string
+	Word        string  `json:"word"`
+	Frequency   float64 `json:"frequency"`
 	occurrences uint64
-	frequency   float64
 }
 
+// statsReport is the JSON output of stats. Frequencies of unusual words are
+// relative to the reference corpus, negative if less frequent.
+type statsReport struct {
+	Since             string            `json:"since,omitempty"`
+	DaysJournaled     float64           `json:"days_journaled_percent"`
+	Words             uint64            `json:"words"`
+	AverageWords      float64           `json:"average_words"`
+	Seconds           int               `json:"seconds"`
+	IdleSeconds       int               `json:"idle_seconds"`
+	GradeLevel        float64           `json:"grade_level"`
+	SentenceLength    float64           `json:"sentence_length"`
+	TypeTokenRatio    float64           `json:"type_token_ratio"`
+	Sentiment         float64           `json:"sentiment"`
+	MoodCorrelation   *float64          `json:"mood_sentiment_correlation,omitempty"`
+	VocabularyGrowth  []vocabularyMonth `json:"vocabulary_growth"`
+	UnusuallyFrequent []*wordStat       `json:"unusually_frequent"`
+	UnusuallyRare     []*wordStat       `json:"unusually_infrequent"`
+	Entries           []entryStats      `json:"entries"`
+}
+
+// entryStats is the JSON output of stats for a single entry.
+type entryStats struct {
+	Date           string  `json:"date"`
+	Words          int     `json:"words"`
+	Sentences      int     `json:"sentences"`
+	Sections       int     `json:"sections,omitempty"`
+	GradeLevel     float64 `json:"grade_level"`
+	SentenceLength float64 `json:"sentence_length"`
+	TypeTokenRatio float64 `json:"type_token_ratio"`
+	Sentiment      float64 `json:"sentiment"`
+	AverageMood    uint8   `json:"average_mood,omitempty"`
+}
+
+// vocabularyMonth is the number of words first used in a month.
+type vocabularyMonth struct {
+	Month    string `json:"month"`
+	NewWords int    `json:"new_words"`
+}
+
+// dateRange is an inclusive range of dates. A zero bound leaves that end of
+// the range open.
+type dateRange struct {
+	from, to time.Time
+}
+
+func (r dateRange) contains(t time.Time) bool {
+	return (r.from.IsZero() || !t.Before(r.from)) && (r.to.IsZero() || !t.After(r.to))
+}
+
+// walkFiles sends the path of each entry in the journal rooted at root, as
+// determined by layout, whose date is within r on paths. Hidden directories
+// such as .git and .gurnel are skipped.
 func walkFiles(
 	done <-chan struct{},
 	root string,
+	layout Layout,
+	r dateRange,
 ) (paths chan string, errc chan error) {
 	paths = make(chan string)
 	errc = make(chan error, 1)
@@ -193,10 +439,20 @@ func walkFiles(
 			if err != nil {
 				return err
 			}
-			if !info.Mode().IsRegular() || visited[info.Name()] || !IsEntry(path) {
+			if info.IsDir() && path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() || visited[rel] {
+				return nil
+			}
+			if date, err := layout.Date(rel); err != nil || !r.contains(date) {
 				return nil
 			}
-			visited[info.Name()] = true
+			visited[rel] = true
 			select {
 			case paths <- path:
 			case <-done:
@@ -208,19 +464,53 @@ func walkFiles(
 	return paths, errc
 }
 
-func entryScanner(done <-chan struct{}, paths <-chan string, c chan<- result) {
+// entryScanner loads the entries named on paths and sends their results on
+// c, scoring their sentiment with lx. Entries for which keep returns false
+// are skipped.
+func entryScanner(
+	done <-chan struct{},
+	j *journal,
+	paths <-chan string,
+	keep func(*Entry) bool,
+	lx lexicon,
+	c chan<- result,
+) {
 	for path := range paths {
-		p := &Entry{Path: path}
+		p := j.entry(path)
 		m := make(map[string]uint64)
 		_, err := p.Load()
+		if err == nil && !keep(p) {
+			continue
+		}
+		var style readability
+		var sentiment float64
+		var parts int
 		if err == nil {
-			for _, word := range p.Words() {
+			parts = sections(p.Body)
+			words := p.Words()
+			for _, word := range words {
 				m[strings.ToLower(string(word))]++
 			}
+			style = measureReadability(words)
+			sentiment = lx.score(words)
 		}
 		date, _ := p.Date()
+		var idle time.Duration
+		for _, s := range p.Sessions {
+			idle += time.Duration(s.Idle)
+		}
 		select {
-		case c <- result{date: date, wordMap: m, err: err}:
+		case c <- result{
+			date:        date,
+			wordMap:     m,
+			timeSpent:   time.Duration(p.TimeSpent),
+			idle:        idle,
+			readability: style,
+			sections:    parts,
+			sentiment:   sentiment,
+			mood:        p.AverageMood,
+			err:         err,
+		}:
 		case <-done:
 			return
 		}
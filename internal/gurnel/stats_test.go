@@ -14,6 +14,7 @@ func TestStats(t *testing.T) {
 	testCases := []struct {
 		desc       string
 		entryWords []string
+		cmd        statsCmd
 		out        []string
 	}{
 		{
@@ -34,6 +35,7 @@ func TestStats(t *testing.T) {
 			entryWords: []string{"foo bar baz"},
 			out: []string{
 				"word count: 3",
+				"time writing: 0s",
 				`100.00% of days`,
 			},
 		},
@@ -53,6 +55,59 @@ func TestStats(t *testing.T) {
 				`50.00% of days`,
 			},
 		},
+		{
+			desc:       "with sentiment",
+			entryWords: []string{"a good day", "not happy"},
+			out: []string{
+				"average sentiment: +0.",
+			},
+		},
+		{
+			desc:       "with readability",
+			entryWords: []string{"The cat sat. It purred!", "The dog ran"},
+			cmd:        statsCmd{entries: true},
+			out: []string{
+				"average sentence length: 2.7 words",
+				"lexical diversity: 0.88",
+				"2008-04  7",
+				"2008-04-12  5      2",
+			},
+		},
+		{
+			desc:       "with JSON output",
+			entryWords: []string{"The cat sat. It purred!"},
+			cmd:        statsCmd{json: true},
+			out: []string{
+				`"sentence_length": 2.5`,
+				`"new_words": 5`,
+				`"date": "2008-04-12"`,
+			},
+		},
+		{
+			desc:       "with sections",
+			entryWords: []string{"## 08:00\none two\n\n## 21:30\nthree", "four"},
+			out: []string{
+				"word count: 4",
+				"days written in several sections: 1",
+			},
+		},
+		{
+			desc:       "with a tag filter",
+			entryWords: []string{"foo #travel", "bar baz qux"},
+			cmd:        statsCmd{tag: "#Travel"},
+			out: []string{
+				"word count: 2",
+				`100.00% of days`,
+			},
+		},
+		{
+			desc:       "with a person filter",
+			entryWords: []string{"alone", "lunch with @Sam"},
+			cmd:        statsCmd{person: "sam"},
+			out: []string{
+				"word count: 3",
+			},
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -67,7 +122,7 @@ func TestStats(t *testing.T) {
 
 				entryTime := testClock.Now().Add(-time.Duration(24*i) * time.Hour)
 				t.Log(entryTime)
-				entry, err := NewEntry(dir, entryTime)
+				entry, err := NewEntry(dir, DefaultLayout, entryTime)
 				if err != nil {
 					t.Fatalf("saving entry: %s", err)
 				}
@@ -75,7 +130,7 @@ func TestStats(t *testing.T) {
 				defer test.WriteFile(t, entry.Path, words)()
 			}
 
-			cmd := statsCmd{}
+			cmd := tC.cmd
 			out := bytes.Buffer{}
 			conf := Config{clock: &testClock}
 			if err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
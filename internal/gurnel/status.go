@@ -0,0 +1,247 @@
+package gurnel
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// statusTimeout bounds how long status waits for Beeminder.
+	statusTimeout = 10 * time.Second
+	// statusCacheFile holds, in a journal's config directory, what status
+	// last found, so that -short needn't wait on Beeminder or on deriving
+	// the journal's key.
+	statusCacheFile = "status.json"
+	// statusCacheFor is how long -short reuses Beeminder goals.
+	statusCacheFor = 5 * time.Minute
+)
+
+type statusCmd struct {
+	short bool
+}
+
+func (*statusCmd) Name() string      { return "status" }
+func (*statusCmd) ShortHelp() string { return "Show today's progress and goal safety" }
+
+func (c *statusCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.BoolVar(&c.short, "short", false, "print a single line, for shell prompts and status bars")
+	return fs
+}
+
+func (*statusCmd) LongHelp() string {
+	return `Shows whether today's entry exists and how many words it has, the current
+streak, uncommitted changes in the journal, Beeminder datapoints and webhooks
+that failed when an entry was committed, and, if Beeminder is enabled, how
+many days remain before each goal derails. Failed integrations aren't retried;
+they're cleared when the entry is committed again and they succeed.
+
+With -short, prints everything on one line, reusing the word count until the
+entry changes and Beeminder goals for up to five minutes, like
+
+  412/750w 6d 2M fail:1 bm:3d
+
+meaning 412 of 750 words written, a 6 day streak, 2 modified files, 1 failed
+integration, and 3 days of safety buffer on the Beeminder goal closest to
+derailing.`
+}
+
+// journalStatus is where the journal stands today.
+type journalStatus struct {
+	exists      bool
+	words       int
+	minimum     int
+	streak      int
+	uncommitted int
+	// gitErr is set if uncommitted changes couldn't be counted, such as
+	// when the journal isn't a git repository.
+	gitErr      error
+	undelivered []undelivered
+	// undeliveredErr is set if failed integrations couldn't be read.
+	undeliveredErr error
+	goals          []*beeminderGoal
+	// goalErr is set if Beeminder is enabled but couldn't be reached.
+	goalErr error
+}
+
+// statusCache is what status last found.
+type statusCache struct {
+	// Words were counted in the entry at Entry, as modified at ModTime.
+	Entry   string
+	ModTime time.Time
+	Words   int
+	Goals   []*beeminderGoal
+	GoalsAt time.Time
+}
+
+func (c *statusCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	j, err := findJournal(conf)
+	if err != nil {
+		return err
+	}
+	cachePath := filepath.Join(j.root, journalConfigDir, statusCacheFile)
+	var cache statusCache
+	if c.short {
+		// A missing or unreadable cache is just rebuilt
+		if data, err := ioutil.ReadFile(cachePath); err == nil {
+			json.Unmarshal(data, &cache)
+		}
+	}
+	now := conf.today()
+	s := journalStatus{minimum: conf.MinimumWordCount, streak: streak(j, now)}
+
+	p := j.entryFor(now)
+	info, err := os.Stat(p.Path)
+	switch {
+	case err == nil && cache.Entry == p.Path && cache.ModTime.Equal(info.ModTime()):
+		s.exists = true
+		s.words = cache.Words
+		s.streak++
+	case err == nil:
+		if err := j.unlock(w, conf); err != nil {
+			return err
+		}
+		p = j.entryFor(now)
+		if _, err := p.Load(); err != nil {
+			return fmt.Errorf("loading today's entry: %w", err)
+		}
+		s.exists = true
+		s.words = len(p.Words())
+		s.streak++
+		cache.Entry, cache.ModTime, cache.Words = p.Path, p.ModTime, s.words
+	case !os.IsNotExist(err):
+		return fmt.Errorf("loading today's entry: %w", err)
+	}
+
+	// The journal's own configuration, backups and locks aren't entries
+	exclude := ":(exclude)" + journalConfigDir
+	if out, err := gitOutput(j.root, "status", "--porcelain", "--", ".", exclude); err != nil {
+		s.gitErr = err
+	} else if out = strings.TrimSpace(out); out != "" {
+		s.uncommitted = len(strings.Split(out, "\n"))
+	}
+
+	s.undelivered, s.undeliveredErr = readUndelivered(j.root)
+
+	if conf.BeeminderEnabled {
+		if age := conf.now().Sub(cache.GoalsAt); cache.Goals != nil && age >= 0 && age < statusCacheFor {
+			s.goals = cache.Goals
+		} else if s.goals, s.goalErr = fetchGoals(w, conf); s.goalErr == nil {
+			cache.Goals, cache.GoalsAt = s.goals, conf.now()
+		}
+	}
+
+	// The cache only saves time, so failing to write it isn't an error
+	if data, err := json.MarshalIndent(&cache, "", "  "); err == nil {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+			writeFileAtomic(cachePath, data)
+		}
+	}
+
+	if c.short {
+		s.printShort(w)
+	} else {
+		s.print(w, now)
+	}
+	return nil
+}
+
+func fetchGoals(w io.Writer, conf *Config) ([]*beeminderGoal, error) {
+	client, err := conf.beeminderClient(w)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	var goals []*beeminderGoal
+	for _, gc := range conf.beeminderGoals() {
+		g, err := client.goal(ctx, gc.Slug)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, nil
+}
+
+func (s *journalStatus) print(w io.Writer, now time.Time) {
+	switch {
+	case !s.exists:
+		fmt.Fprintf(w, "No entry yet for %s\n", now.Format("Monday, January 2"))
+	case s.words >= s.minimum:
+		fmt.Fprintf(w, "Today's entry: %d words (minimum %d reached)\n", s.words, s.minimum)
+	default:
+		fmt.Fprintf(w, "Today's entry: %d of %d words\n", s.words, s.minimum)
+	}
+	fmt.Fprintf(w, "Streak: %d %s\n", s.streak, plural(s.streak, "day"))
+	switch {
+	case s.gitErr != nil:
+		fmt.Fprintf(w, "Uncommitted changes: unknown (%s)\n", s.gitErr)
+	case s.uncommitted == 0:
+		fmt.Fprintln(w, "No uncommitted changes")
+	default:
+		fmt.Fprintf(w, "Uncommitted changes: %d %s\n", s.uncommitted, plural(s.uncommitted, "file"))
+	}
+	if s.undeliveredErr != nil {
+		fmt.Fprintf(w, "Failed integrations: unknown (%s)\n", s.undeliveredErr)
+	}
+	for _, u := range s.undelivered {
+		fmt.Fprintf(w, "Failed integration: %s for %s, on %s\n",
+			u.Integration, u.Entry, u.Failed.Format("Jan 2 15:04"))
+	}
+	if s.goalErr != nil {
+		fmt.Fprintf(w, "Beeminder: unavailable (%s)\n", s.goalErr)
+	}
+	for _, g := range s.goals {
+		fmt.Fprintf(w, "Beeminder: %d %s of safety buffer on %s, derails %s",
+			g.SafeBuf, plural(g.SafeBuf, "day"), g.Slug, g.Derails().Format("Jan 2"))
+		if g.Limsum != "" {
+			fmt.Fprintf(w, " (%s)", g.Limsum)
+		}
+		fmt.Fprint(w, "\n")
+	}
+}
+
+func (s *journalStatus) printShort(w io.Writer) {
+	fields := []string{
+		fmt.Sprintf("%d/%dw", s.words, s.minimum),
+		fmt.Sprintf("%dd", s.streak),
+	}
+	if s.uncommitted > 0 {
+		fields = append(fields, fmt.Sprintf("%dM", s.uncommitted))
+	}
+	switch {
+	case s.undeliveredErr != nil:
+		fields = append(fields, "fail:?")
+	case len(s.undelivered) > 0:
+		fields = append(fields, fmt.Sprintf("fail:%d", len(s.undelivered)))
+	}
+	switch {
+	case s.goalErr != nil:
+		fields = append(fields, "bm:?")
+	case len(s.goals) > 0:
+		safeBuf := s.goals[0].SafeBuf
+		for _, g := range s.goals[1:] {
+			if g.SafeBuf < safeBuf {
+				safeBuf = g.SafeBuf
+			}
+		}
+		fields = append(fields, fmt.Sprintf("bm:%dd", safeBuf))
+	}
+	fmt.Fprintln(w, strings.Join(fields, " "))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
@@ -0,0 +1,188 @@
+package gurnel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestStatus(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		bodies    []string
+		template  string
+		repo      bool
+		beeminder bool
+		offline   bool
+		failed    string
+		short     bool
+		out       []string
+		absent    []string
+	}{
+		{
+			desc: "with no entries",
+			out:  []string{"no entry yet for saturday, april 12", "streak: 0 days", "uncommitted changes: unknown"},
+		},
+		{
+			desc:   "with entries in a repository",
+			bodies: []string{"one two", "one two three"},
+			repo:   true,
+			out:    []string{"today's entry: 3 of 750 words", "streak: 2 days", "uncommitted changes: 2 files"},
+		},
+		{
+			desc:     "with only the template written",
+			bodies:   []string{"## What went well?\n"},
+			template: "## What went well?\n",
+			repo:     true,
+			out:      []string{"today's entry: 0 of 750 words", "uncommitted changes: 1 file\n"},
+		},
+		{
+			desc:      "with Beeminder enabled",
+			bodies:    []string{"one two three"},
+			beeminder: true,
+			out:       []string{"beeminder: 3 days of safety buffer on writing", "(+750 due in 3 days)"},
+		},
+		{
+			desc:      "with Beeminder unreachable",
+			bodies:    []string{"one two three"},
+			beeminder: true,
+			offline:   true,
+			out:       []string{"beeminder: unavailable"},
+			absent:    []string{"s3cret"},
+		},
+		{
+			desc:   "with a failed integration",
+			bodies: []string{"one two three"},
+			failed: "webhooks",
+			out:    []string{"failed integration: webhooks for 2008-04-12, on apr 12 16:00"},
+		},
+		{
+			desc:   "with a failed integration and short output",
+			bodies: []string{"one two three"},
+			failed: "Beeminder",
+			short:  true,
+			out:    []string{"3/750w 1d fail:1\n"},
+		},
+		{
+			desc:      "with short output",
+			bodies:    []string{"one two", "one two three"},
+			repo:      true,
+			beeminder: true,
+			short:     true,
+			out:       []string{"3/750w 2d 2M bm:3d\n"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			if tC.repo {
+				if err := git(dir, "init", "-q"); err != nil {
+					t.Fatalf("setting up repository: %s", err)
+				}
+			}
+			if tC.template != "" {
+				tmplDir := filepath.Join(dir, journalConfigDir, templateDir)
+				if err := os.MkdirAll(tmplDir, 0700); err != nil {
+					t.Fatalf("creating template dir: %s", err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(tmplDir, defaultTemplate), []byte(tC.template), 0600); err != nil {
+					t.Fatalf("writing template: %s", err)
+				}
+			}
+			writeTestEntries(t, dir, tC.bodies...)
+
+			conf := Config{
+				MinimumWordCount: 750,
+				clock:            &test.FixedClock{},
+			}
+			if tC.failed != "" {
+				if err := recordDelivery(dir, tC.failed, "2008-04-12", conf.now(), true); err != nil {
+					t.Fatalf("recording failure: %s", err)
+				}
+			}
+			if tC.beeminder {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(`{"slug":"writing","safebuf":3,"losedate":1208217600,"limsum":"+750 due in 3 days"}`))
+				}))
+				defer server.Close()
+				conf.BeeminderEnabled = true
+				conf.BeeminderUser = "alice"
+				conf.BeeminderGoal = "writing"
+				conf.BeeminderToken = CredentialConfig{Source: "command", Command: "echo s3cret"}
+				conf.beeminderURL = server.URL
+				if tC.offline {
+					server.Close()
+				}
+			}
+
+			cmd := statusCmd{short: tC.short}
+			out := bytes.Buffer{}
+			if err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, tC.out, out.String())
+			for _, absent := range tC.absent {
+				if strings.Contains(out.String(), absent) {
+					t.Fatalf("expected output not to contain %q. got %q", absent, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestStatusShortCache(t *testing.T) {
+	_, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	os.Setenv(passphraseEnv, "correct horse")
+	defer os.Unsetenv(passphraseEnv)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"slug":"writing","safebuf":3}`))
+	}))
+	conf := Config{
+		MinimumWordCount: 750,
+		BeeminderEnabled: true,
+		BeeminderUser:    "alice",
+		BeeminderGoal:    "writing",
+		BeeminderToken:   CredentialConfig{Source: "command", Command: "echo s3cret"},
+		beeminderURL:     server.URL,
+		clock:            &test.FixedClock{},
+	}
+	conf.Encryption.Enabled = true
+	j, err := openJournal(ioutil.Discard, &conf)
+	if err != nil {
+		t.Fatalf("opening journal: %s", err)
+	}
+	p, err := j.newEntry(conf.now())
+	if err != nil {
+		t.Fatalf("creating entry: %s", err)
+	}
+	p.Body = []byte("one two three")
+	if err := p.Save(); err != nil {
+		t.Fatalf("saving entry: %s", err)
+	}
+
+	cmd := statusCmd{short: true}
+	out := bytes.Buffer{}
+	if err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	test.CheckOutput(t, []string{"3/750w 1d bm:3d\n"}, out.String())
+
+	// Without the passphrase or Beeminder, the cached results are shown
+	os.Unsetenv(passphraseEnv)
+	server.Close()
+	out.Reset()
+	if err := cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	test.CheckOutput(t, []string{"3/750w 1d bm:3d\n"}, out.String())
+}
@@ -0,0 +1,188 @@
+package gurnel
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	// A tag starts with a letter, and mustn't follow a character that makes
+	// it part of a word, URL fragment, or HTML entity.
+	tagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#@])#(\p{L}[\p{L}\p{N}_/-]*)`)
+	// A mention mustn't follow a character that makes it part of an email
+	// address.
+	mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@(\p{L}[\p{L}\p{N}_-]*)`)
+	codeSpan     = regexp.MustCompile("`+[^`]*`+")
+	heading      = regexp.MustCompile(`^ {0,3}#{1,6}(?:\s|$)`)
+)
+
+// extractTags returns the #tags and @mentions in the Markdown body, in order
+// of first appearance. Tags are lowercased. Code and headings are ignored.
+func extractTags(body []byte) (tags, people []string) {
+	seenTags := make(map[string]bool)
+	seenPeople := make(map[string]bool)
+	var fence string
+	for _, line := range strings.Split(string(body), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			continue
+		case strings.HasPrefix(line, "    "), strings.HasPrefix(line, "\t"), heading.MatchString(line):
+			continue
+		}
+
+		line = codeSpan.ReplaceAllString(line, " ")
+		for _, m := range tagRegex.FindAllStringSubmatch(line, -1) {
+			tag := strings.ToLower(strings.TrimRight(m[1], "/-"))
+			if !seenTags[tag] {
+				seenTags[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		for _, m := range mentionRegex.FindAllStringSubmatch(line, -1) {
+			person := strings.TrimRight(m[1], "-")
+			if !seenPeople[person] {
+				seenPeople[person] = true
+				people = append(people, person)
+			}
+		}
+	}
+	return tags, people
+}
+
+// ExtractTags adds the #tags and @mentions in p.Body to p.Tags and p.People.
+func (p *Entry) ExtractTags() {
+	tags, people := extractTags(p.Body)
+	p.Tags = mergeTags(p.Tags, tags)
+	p.People = mergeTags(p.People, people)
+}
+
+// allTags returns p's tags, from both its frontmatter and its body.
+func (p *Entry) allTags() []string {
+	tags, _ := extractTags(p.Body)
+	return mergeTags(lowerAll(p.Tags), tags)
+}
+
+// allPeople returns the people p mentions, in either its frontmatter or its
+// body.
+func (p *Entry) allPeople() []string {
+	_, people := extractTags(p.Body)
+	return mergeTags(p.People, people)
+}
+
+func lowerAll(s []string) []string {
+	lower := make([]string, len(s))
+	for i := range s {
+		lower[i] = strings.ToLower(s[i])
+	}
+	return lower
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type tagsCmd struct {
+	people bool
+}
+
+func (*tagsCmd) Name() string      { return "tags" }
+func (*tagsCmd) ShortHelp() string { return "List tags and mentions used in the journal" }
+
+func (c *tagsCmd) Flag() flag.FlagSet {
+	fs := flag.FlagSet{}
+	fs.BoolVar(&c.people, "people", false, "list @mentions instead of #tags")
+	return fs
+}
+
+func (*tagsCmd) LongHelp() string {
+	return `Lists the tags used in the journal, with the number of entries using each and
+when it was last used. Tags are read from entries' frontmatter, and from
+#words in their text outside of code and headings. With -people, lists
+@mentions instead.
+
+Set ExtractTags in your config to save the tags and mentions found in an
+entry's text to its frontmatter when it's written. They aren't saved for
+encrypted entries, whose frontmatter isn't encrypted.`
+}
+
+// tagCount is the use of a tag in the journal.
+type tagCount struct {
+	name     string
+	count    int
+	lastUsed time.Time
+}
+
+func (c *tagsCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]*tagCount)
+	err = streamEntries(j, dateRange{}, func(p *Entry) error {
+		date, err := p.Date()
+		if err != nil {
+			return err
+		}
+		names := p.allTags()
+		if c.people {
+			names = p.allPeople()
+		}
+		for _, name := range names {
+			tc, ok := counts[strings.ToLower(name)]
+			if !ok {
+				tc = &tagCount{name: name}
+				counts[strings.ToLower(name)] = tc
+			}
+			tc.count++
+			tc.lastUsed = date // Entries are streamed oldest first
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix, noun := "#", "tags"
+	if c.people {
+		prefix, noun = "@", "mentions"
+	}
+	if len(counts) == 0 {
+		fmt.Fprintf(w, "No %s found\n", noun)
+		return nil
+	}
+	sorted := make([]*tagCount, 0, len(counts))
+	for _, tc := range counts {
+		sorted = append(sorted, tc)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, tc := range sorted {
+		fmt.Fprintf(out, "%s%s\t%d\t%s\n", prefix, tc.name, tc.count, tc.lastUsed.Format(dateArgFormat))
+	}
+	return out.Flush()
+}
@@ -0,0 +1,86 @@
+package gurnel
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestExtractTags(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		body   string
+		tags   []string
+		people []string
+	}{
+		{
+			desc:   "with tags and mentions",
+			body:   "Hiked with @Sam and @jo-ann. #Travel #hiking, #travel again\n",
+			tags:   []string{"travel", "hiking"},
+			people: []string{"Sam", "jo-ann"},
+		},
+		{
+			desc: "with headings",
+			body: "# Heading #notatag\n## Another\n#tag at the start\n",
+			tags: []string{"tag"},
+		},
+		{
+			desc: "with code",
+			body: "```\n#include <stdio.h>\n@decorator\n```\n    #indented\nsome `#inline` code #real\n",
+			tags: []string{"real"},
+		},
+		{
+			desc: "with things that look like tags",
+			body: "mail me@example.com, see http://example.com/#anchor or page#2, &#123; #1 issue\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tags, people := extractTags([]byte(tC.body))
+			if !reflect.DeepEqual(tags, tC.tags) {
+				t.Fatalf("wrong tags. expected %q. got %q", tC.tags, tags)
+			}
+			if !reflect.DeepEqual(people, tC.people) {
+				t.Fatalf("wrong people. expected %q. got %q", tC.people, people)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	testCases := []struct {
+		desc string
+		cmd  tagsCmd
+		out  []string
+	}{
+		{
+			desc: "with tags",
+			out:  []string{"#travel  2  2008-04-12", "#work    1  2008-04-12"},
+		},
+		{
+			desc: "with people",
+			cmd:  tagsCmd{people: true},
+			out:  []string{"@Sam  1  2008-04-11"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			entries := writeTestEntries(t, dir, "#travel", "met @Sam", "back at #work")
+			entries[2].Tags = []string{"Travel"}
+			if err := entries[2].Save(); err != nil {
+				t.Fatalf("saving entry: %s", err)
+			}
+
+			out := bytes.Buffer{}
+			conf := Config{clock: &test.FixedClock{}}
+			if err := tC.cmd.Run(&bytes.Buffer{}, &out, []string{}, &conf); err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+			test.CheckOutput(t, tC.out, out.String())
+		})
+	}
+}
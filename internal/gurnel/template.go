@@ -0,0 +1,191 @@
+package gurnel
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	journalConfigDir = ".gurnel"
+	templateDir      = "templates"
+	defaultTemplate  = "default.md"
+	promptsFile      = "prompts.txt"
+)
+
+// templateData is the data made available to entry templates.
+type templateData struct {
+	Date      time.Time
+	Yesterday string
+	Streak    int
+	LastMood  uint8
+	Prompt    string
+}
+
+// renderTemplate renders the journal's entry template for the date t. A
+// template named for the weekday (e.g. friday.md) takes precedence over
+// default.md. If neither exists, renderTemplate returns nil.
+func renderTemplate(j *journal, t time.Time) ([]byte, error) {
+	tmplDir := filepath.Join(j.root, journalConfigDir, templateDir)
+	var text []byte
+	for _, name := range []string{
+		strings.ToLower(t.Weekday().String()) + ".md",
+		defaultTemplate,
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(tmplDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading template: %w", err)
+		}
+		text = data
+		break
+	}
+	if text == nil {
+		return nil, nil
+	}
+
+	t = startOfDay(t)
+	data := templateData{
+		Date:     t,
+		Streak:   streak(j, t),
+		LastMood: lastMood(j, t),
+	}
+	yesterday := j.layout.Path(t.AddDate(0, 0, -1))
+	if _, err := os.Stat(filepath.Join(j.root, yesterday)); err == nil {
+		link, err := filepath.Rel(filepath.Dir(j.layout.Path(t)), yesterday)
+		if err != nil {
+			return nil, err
+		}
+		data.Yesterday = filepath.ToSlash(link)
+	}
+	prompt, err := dailyPrompt(j.root, t)
+	if err != nil {
+		return nil, err
+	}
+	data.Prompt = prompt
+
+	tmpl, err := template.New("entry").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// dailyPrompt returns the writing prompt for the date t, rotating through the
+// non-blank lines of the journal's prompts file one day at a time.
+func dailyPrompt(dir string, t time.Time) (string, error) {
+	f, err := os.Open(filepath.Join(dir, journalConfigDir, promptsFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("opening prompts: %w", err)
+	}
+	defer f.Close()
+
+	var prompts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			prompts = append(prompts, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("reading prompts: %w", err)
+	}
+	if len(prompts) == 0 {
+		return "", nil
+	}
+
+	days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+	return prompts[days%int64(len(prompts))], nil
+}
+
+// streak returns the number of consecutive days with an entry ending the day
+// before t.
+func streak(j *journal, t time.Time) (n int) {
+	for d := t.AddDate(0, 0, -1); ; d = d.AddDate(0, 0, -1) {
+		if _, err := os.Stat(j.entryFor(d).Path); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+// lastMood returns the average mood of the most recent entry from the year
+// before t that has one recorded, or zero if there is none.
+func lastMood(j *journal, t time.Time) uint8 {
+	for d := t.AddDate(0, 0, -1); d.After(t.AddDate(-1, 0, 0)); d = d.AddDate(0, 0, -1) {
+		p := j.entryFor(d)
+		if _, err := p.Load(); err != nil {
+			continue
+		}
+		if p.AverageMood != 0 {
+			return p.AverageMood
+		}
+	}
+	return 0
+}
+
+// templateLineHash returns a short hash of a line of a template, ignoring
+// the whitespace around it.
+func templateLineHash(line []byte) string {
+	sum := sha256.Sum256(bytes.TrimSpace(line))
+	return hex.EncodeToString(sum[:4])
+}
+
+// templateLines returns the hashes of the non-blank lines of tmpl, in order.
+// New entries record them, so that the lines they were started with can be
+// told apart from what was written, without keeping the template's text.
+func templateLines(tmpl []byte) []string {
+	var lines []string
+	for _, line := range bytes.Split(tmpl, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, templateLineHash(line))
+		}
+	}
+	return lines
+}
+
+// stripTemplate returns body without the lines left unchanged from the
+// template whose line hashes are lines. Lines are matched in template order,
+// and each line of the template matches at most once, so a line of the
+// template that is written again elsewhere is kept.
+func stripTemplate(body []byte, lines []string) []byte {
+	var out [][]byte
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		matched := false
+		if len(bytes.TrimSpace(line)) > 0 {
+			hash := templateLineHash(line)
+			for i, tmplLine := range lines {
+				if hash == tmplLine {
+					lines = lines[i+1:]
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			out = append(out, line)
+		}
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,184 @@
+package gurnel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestNewEntryTemplate(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		templates map[string]string
+		prompts   string
+		previous  int
+		body      []string
+	}{
+		{
+			desc: "with no template",
+			body: []string{},
+		},
+		{
+			desc:      "with a default template",
+			templates: map[string]string{"default.md": "# {{.Date.Format \"Jan 2\"}}\n"},
+			body:      []string{"# Apr 12"},
+		},
+		{
+			desc: "with a weekday template",
+			templates: map[string]string{
+				"default.md":  "daily\n",
+				"saturday.md": "weekly review\n",
+			},
+			body: []string{"weekly review"},
+		},
+		{
+			desc:      "with prompts",
+			templates: map[string]string{"default.md": "{{.Prompt}}\n"},
+			prompts:   "first\n\nsecond\nthird\n",
+			body:      []string{"second"},
+		},
+		{
+			desc:      "with previous entries",
+			templates: map[string]string{"default.md": "{{.Streak}} {{.Yesterday}}\n"},
+			previous:  2,
+			body:      []string{"2 2008-04-11-Journal-Entry-for-Apr-11.md"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			now := (&test.FixedClock{}).Now()
+
+			tmplDir := filepath.Join(dir, journalConfigDir, templateDir)
+			if err := os.MkdirAll(tmplDir, 0700); err != nil {
+				t.Fatalf("creating template dir: %s", err)
+			}
+			for name, text := range tC.templates {
+				if err := ioutil.WriteFile(filepath.Join(tmplDir, name), []byte(text), 0600); err != nil {
+					t.Fatalf("writing template: %s", err)
+				}
+			}
+			if tC.prompts != "" {
+				promptPath := filepath.Join(dir, journalConfigDir, promptsFile)
+				if err := ioutil.WriteFile(promptPath, []byte(tC.prompts), 0600); err != nil {
+					t.Fatalf("writing prompts: %s", err)
+				}
+			}
+			for i := 1; i <= tC.previous; i++ {
+				if _, err := NewEntry(dir, DefaultLayout, now.AddDate(0, 0, -i)); err != nil {
+					t.Fatalf("creating previous entry: %s", err)
+				}
+			}
+
+			p, err := NewEntry(dir, DefaultLayout, now)
+			if err != nil {
+				t.Fatalf("expected no error. got %s", err)
+			}
+
+			test.CheckOutput(t, tC.body, string(p.Body))
+			if n := len(p.Words()); n != 0 {
+				t.Fatalf("expected template to contribute no words. got %d", n)
+			}
+		})
+	}
+}
+
+func TestWordsExcludesTemplate(t *testing.T) {
+	tmpl := "## What went well?\n\n## What didn't?\n"
+	testCases := []struct {
+		desc  string
+		body  string
+		words int
+	}{
+		{
+			desc:  "with the template unchanged",
+			body:  tmpl,
+			words: 0,
+		},
+		{
+			desc:  "with text under the template's headings",
+			body:  "## What went well?\nshipped the release\n## What didn't?\n",
+			words: 3,
+		},
+		{
+			desc:  "with a heading of the template written twice",
+			body:  "## What went well?\nlunch\n## What didn't?\n## What went well?\n",
+			words: 5,
+		},
+		{
+			desc:  "with the template's headings reordered",
+			body:  "## What didn't?\nrain\n## What went well?\n",
+			words: 5,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := &Entry{Body: []byte(tC.body), TemplateLines: templateLines([]byte(tmpl))}
+			if words := p.Words(); len(words) != tC.words {
+				t.Fatalf("expected %d words. got %d: %q", tC.words, len(words), words)
+			}
+		})
+	}
+}
+
+func TestWordsExcludesTemplateOfLoadedEntry(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	now := (&test.FixedClock{}).Now()
+
+	tmplDir := filepath.Join(dir, journalConfigDir, templateDir)
+	if err := os.MkdirAll(tmplDir, 0700); err != nil {
+		t.Fatalf("creating template dir: %s", err)
+	}
+	tmpl := "# {{.Date.Format \"Jan 2\"}}\n## What went well?\n"
+	if err := ioutil.WriteFile(filepath.Join(tmplDir, defaultTemplate), []byte(tmpl), 0600); err != nil {
+		t.Fatalf("writing template: %s", err)
+	}
+	if _, err := NewEntry(dir, DefaultLayout, now); err != nil {
+		t.Fatalf("creating entry: %s", err)
+	}
+
+	j := &journal{root: dir, layout: DefaultLayout}
+	p := j.entryFor(now)
+	if _, err := p.Load(); err != nil {
+		t.Fatalf("loading entry: %s", err)
+	}
+	if words := p.Words(); len(words) != 0 {
+		t.Fatalf("expected no words. got %q", words)
+	}
+}
+
+func TestWordsKeepsTemplateAddedLater(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	now := (&test.FixedClock{}).Now()
+
+	body := "## What went well?\n"
+	p, err := NewEntry(dir, DefaultLayout, now)
+	if err != nil {
+		t.Fatalf("creating entry: %s", err)
+	}
+	p.Body = []byte(body)
+	if err := p.Save(); err != nil {
+		t.Fatalf("saving entry: %s", err)
+	}
+	tmplDir := filepath.Join(dir, journalConfigDir, templateDir)
+	if err := os.MkdirAll(tmplDir, 0700); err != nil {
+		t.Fatalf("creating template dir: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmplDir, defaultTemplate), []byte(body), 0600); err != nil {
+		t.Fatalf("writing template: %s", err)
+	}
+
+	p, err = NewEntry(dir, DefaultLayout, now)
+	if err != nil {
+		t.Fatalf("loading entry: %s", err)
+	}
+	if words := p.Words(); len(words) != 4 {
+		t.Fatalf("expected 4 words. got %q", words)
+	}
+}
@@ -0,0 +1,68 @@
+package gurnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// undeliveredFile lists, in a journal's config directory, the integrations
+// that failed after entries were committed.
+const undeliveredFile = "undelivered.json"
+
+// undelivered is an integration that failed after an entry was committed.
+// It isn't retried, but is cleared once the entry is committed again and the
+// integration succeeds.
+type undelivered struct {
+	Integration string
+	Entry       string
+	Failed      time.Time
+}
+
+func readUndelivered(root string) ([]undelivered, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, journalConfigDir, undeliveredFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading undelivered integrations: %w", err)
+	}
+	var list []undelivered
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parsing undelivered integrations: %w", err)
+	}
+	return list, nil
+}
+
+// recordDelivery records whether integration went out for the entry dated
+// day, replacing what was recorded for them before.
+func recordDelivery(root, integration, day string, now time.Time, failed bool) error {
+	list, err := readUndelivered(root)
+	if err != nil {
+		return err
+	}
+	kept := list[:0]
+	for _, u := range list {
+		if u.Integration != integration || u.Entry != day {
+			kept = append(kept, u)
+		}
+	}
+	if !failed && len(kept) == len(list) {
+		return nil
+	}
+	if failed {
+		kept = append(kept, undelivered{Integration: integration, Entry: day, Failed: now})
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, journalConfigDir, undeliveredFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,222 @@
+package gurnel
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds each delivery attempt.
+	webhookTimeout = 10 * time.Second
+	// defaultWebhookRetries is how many times a failed delivery is retried,
+	// unless configured otherwise.
+	defaultWebhookRetries = 3
+	// signatureHeader carries the HMAC-SHA256 signature of the payload.
+	signatureHeader = "X-Gurnel-Signature"
+	// eventHeader carries the payload's event.
+	eventHeader = "X-Gurnel-Event"
+)
+
+// webhookBackoff is the delay before the first retry of a failed delivery.
+// It doubles with each retry.
+var webhookBackoff = time.Second
+
+// WebhookConfig describes an HTTP endpoint that is sent a JSON payload when
+// an entry is committed.
+type WebhookConfig struct {
+	URL string
+	// Secret, if configured, signs payloads with HMAC-SHA256. The signature
+	// is sent in the X-Gurnel-Signature header as "sha256=" and the hex
+	// digest of the request body.
+	Secret CredentialConfig
+	// IncludeBody adds the entry's text to the payload.
+	IncludeBody bool
+	// Retries is how many times a failed delivery is retried. It defaults to
+	// 3; a negative number disables retries.
+	Retries int
+}
+
+// webhookPayload is the JSON sent to webhooks.
+type webhookPayload struct {
+	Event       string `json:"event"`
+	Date        string `json:"date"`
+	Words       int    `json:"words"`
+	Seconds     int    `json:"seconds"`
+	LowMood     uint8  `json:"low_mood"`
+	HighMood    uint8  `json:"high_mood"`
+	AverageMood uint8  `json:"average_mood"`
+	Body        string `json:"body,omitempty"`
+}
+
+func newWebhookPayload(event string, p *Entry, includeBody bool) *webhookPayload {
+	payload := &webhookPayload{
+		Event:       event,
+		Words:       len(p.Words()),
+		Seconds:     int(time.Duration(p.TimeSpent).Seconds()),
+		LowMood:     p.LowMood,
+		HighMood:    p.HighMood,
+		AverageMood: p.AverageMood,
+	}
+	if date, err := p.Date(); err == nil {
+		payload.Date = date.Format(dateArgFormat)
+	}
+	if includeBody {
+		payload.Body = string(p.Body)
+	}
+	return payload
+}
+
+// sign returns the signature of body with secret.
+func sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the event for p to the webhook, retrying with exponential
+// backoff if the endpoint can't be reached or returns a server error.
+func (wc *WebhookConfig) deliver(ctx context.Context, w io.Writer, event string, p *Entry) error {
+	body, err := json.Marshal(newWebhookPayload(event, p, wc.IncludeBody))
+	if err != nil {
+		return err
+	}
+	var signature string
+	if wc.Secret.Source != "" {
+		secret, err := wc.Secret.credential(w)
+		if err != nil {
+			return fmt.Errorf("getting secret: %w", err)
+		}
+		signature = sign(secret, body)
+	}
+	retries := wc.Retries
+	if retries == 0 {
+		retries = defaultWebhookRetries
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	backoff := webhookBackoff
+	for attempt := 0; ; attempt++ {
+		err = wc.post(ctx, &client, event, body, signature)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return fmt.Errorf("waiting to retry: %w", ctx.Err())
+		}
+	}
+}
+
+// permanentError is a delivery failure that retrying won't fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (wc *WebhookConfig) post(ctx context.Context, client *http.Client, event string, body []byte, signature string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wc.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("creating request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gurnel")
+	req.Header.Set(eventHeader, event)
+	if signature != "" {
+		req.Header.Set(signatureHeader, signature)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	respData, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil || len(respData) == 0 {
+		respData = []byte("no further info")
+	}
+	err = fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(respData))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return &permanentError{err}
+}
+
+// fireWebhooks delivers the event for p to each webhook, reporting failures
+// to w. Failing webhooks don't stop the others from being tried.
+func fireWebhooks(ctx context.Context, w io.Writer, hooks []WebhookConfig, event string, p *Entry) error {
+	var failed int
+	for i := range hooks {
+		if err := hooks[i].deliver(ctx, w, event, p); err != nil {
+			fmt.Fprintf(w, "Webhook %s failed: %s\n", hooks[i].URL, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d webhooks failed", failed, len(hooks))
+	}
+	return nil
+}
+
+type webhookCmd struct{}
+
+func (*webhookCmd) Name() string       { return "webhook" }
+func (*webhookCmd) ShortHelp() string  { return "Test the configured webhooks" }
+func (*webhookCmd) Flag() flag.FlagSet { return flag.FlagSet{} }
+
+func (*webhookCmd) LongHelp() string {
+	return `Webhooks listed in Webhooks are sent a JSON payload whenever an entry is
+committed, with its date, word count, seconds spent writing, and moods.
+
+  test   sends a "test" event for today's entry, or an empty entry if there
+         isn't one yet, to each webhook and reports the result`
+}
+
+func (*webhookCmd) Run(_ io.Reader, w io.Writer, args []string, conf *Config) error {
+	if len(args) != 1 || args[0] != "test" {
+		return errors.New("usage: gurnel webhook test")
+	}
+	if len(conf.Webhooks) == 0 {
+		return errors.New("no webhooks configured. Add them to Webhooks in your config")
+	}
+	j, err := openJournal(w, conf)
+	if err != nil {
+		return err
+	}
+	p := j.entryFor(conf.today())
+	if _, err := p.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("loading today's entry: %w", err)
+	}
+
+	var failed int
+	for i := range conf.Webhooks {
+		hook := &conf.Webhooks[i]
+		if err := hook.deliver(context.Background(), w, "test", p); err != nil {
+			fmt.Fprintf(w, "%s: %s\n", hook.URL, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(w, "%s: ok\n", hook.URL)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d webhooks failed", failed, len(conf.Webhooks))
+	}
+	return nil
+}
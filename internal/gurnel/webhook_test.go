@@ -0,0 +1,181 @@
+package gurnel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mikeraimondi/gurnel/internal/test"
+)
+
+func TestWebhookDeliver(t *testing.T) {
+	webhookBackoff = time.Millisecond
+	defer func() { webhookBackoff = time.Second }()
+
+	testCases := []struct {
+		desc     string
+		hook     WebhookConfig
+		statuses []int
+		requests int
+		payload  *webhookPayload
+		err      string
+	}{
+		{
+			desc: "with a signed payload including the body",
+			hook: WebhookConfig{
+				Secret:      CredentialConfig{Source: "command", Command: "echo s3cret"},
+				IncludeBody: true,
+			},
+			requests: 1,
+			payload: &webhookPayload{
+				Event:       "entry.committed",
+				Date:        "2008-04-12",
+				Words:       3,
+				Seconds:     90,
+				AverageMood: 1,
+				Body:        "one two three",
+			},
+		},
+		{
+			desc:     "with a server error that clears",
+			statuses: []int{500, 503},
+			requests: 3,
+		},
+		{
+			desc:     "with a server error that doesn't clear",
+			hook:     WebhookConfig{Retries: 2},
+			statuses: []int{500, 500, 500, 500},
+			requests: 3,
+			err:      "500",
+		},
+		{
+			desc:     "with retries disabled",
+			hook:     WebhookConfig{Retries: -1},
+			statuses: []int{500},
+			requests: 1,
+			err:      "500",
+		},
+		{
+			desc:     "with a client error",
+			statuses: []int{404},
+			requests: 1,
+			err:      "404",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dir, cleanup := test.SetupTestDir(t)
+			defer cleanup()
+			p := writeTestEntries(t, dir, "one two three")[0]
+			p.TimeSpent = Duration(90 * time.Second)
+
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := ioutil.ReadAll(r.Body)
+				if tC.hook.Secret.Source != "" {
+					if got, want := r.Header.Get(signatureHeader), sign([]byte("s3cret"), body); got != want {
+						t.Errorf("wrong signature. expected %s. got %s", want, got)
+					}
+				}
+				if tC.payload != nil {
+					var got webhookPayload
+					if err := json.Unmarshal(body, &got); err != nil {
+						t.Errorf("decoding payload: %s", err)
+					}
+					if !reflect.DeepEqual(&got, tC.payload) {
+						t.Errorf("wrong payload. expected %+v. got %+v", tC.payload, got)
+					}
+				}
+				if requests <= len(tC.statuses) {
+					w.WriteHeader(tC.statuses[requests-1])
+				}
+			}))
+			defer server.Close()
+
+			tC.hook.URL = server.URL
+			err := tC.hook.deliver(context.Background(), ioutil.Discard, "entry.committed", p)
+			test.CheckErr(t, tC.err, err)
+			if requests != tC.requests {
+				t.Fatalf("expected %d requests. got %d", tC.requests, requests)
+			}
+		})
+	}
+}
+
+func TestWebhookTest(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	writeTestEntries(t, dir, "one two three")
+
+	var event string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event = r.Header.Get(eventHeader)
+	}))
+	defer server.Close()
+
+	cmd := webhookCmd{}
+	out := bytes.Buffer{}
+	conf := Config{
+		Webhooks: []WebhookConfig{{URL: server.URL}},
+		clock:    &test.FixedClock{},
+	}
+	if err := cmd.Run(&bytes.Buffer{}, &out, []string{"test"}, &conf); err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	test.CheckOutput(t, []string{server.URL + ": ok"}, out.String())
+	if event != "test" {
+		t.Fatalf("expected a test event. got %q", event)
+	}
+}
+
+func TestAfterCommit(t *testing.T) {
+	dir, cleanup := test.SetupTestDir(t)
+	defer cleanup()
+	p := writeTestEntries(t, dir, "one two three")[0]
+
+	var delivered bool
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	conf := Config{
+		BeeminderEnabled: true,
+		BeeminderUser:    "alice",
+		BeeminderToken:   CredentialConfig{Source: "command", Command: "exit 1"},
+		Webhooks:         []WebhookConfig{{URL: server.URL}},
+		clock:            &test.FixedClock{},
+	}
+	err := afterCommit(ioutil.Discard, &conf, p)
+	test.CheckErr(t, "reading token", err)
+	if !delivered {
+		t.Fatal("expected the webhook to be sent despite the Beeminder failure")
+	}
+
+	status = http.StatusNotFound
+	err = afterCommit(ioutil.Discard, &conf, p)
+	test.CheckErr(t, "reading token", err)
+	test.CheckErr(t, "1 of 1 webhooks failed", err)
+	if list, _ := readUndelivered(dir); len(list) != 2 {
+		t.Fatalf("expected 2 failed integrations. got %v", list)
+	}
+
+	conf.BeeminderEnabled = false
+	status = http.StatusOK
+	if err := afterCommit(ioutil.Discard, &conf, p); err != nil {
+		t.Fatalf("expected no error. got %s", err)
+	}
+	list, _ := readUndelivered(dir)
+	if len(list) != 1 || list[0].Integration != "Beeminder" {
+		t.Fatalf("expected only Beeminder to have failed. got %v", list)
+	}
+}